@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	inputImportPath  = bappaModuleRoot + "/blueprint/input"
+	ebitenImportPath = "github.com/hajimehoshi/ebiten/v2"
+)
+
+// Names of the ebiten.Key constants, without their Key prefix
+var ebitenKeys = strings.Fields(`
+	A B C D E F G H I J K L M N O P Q R S T U V W X Y Z
+	0 1 2 3 4 5 6 7 8 9 Digit0 Digit1 Digit2 Digit3 Digit4 Digit5 Digit6 Digit7 Digit8 Digit9
+	F1 F2 F3 F4 F5 F6 F7 F8 F9 F10 F11 F12 F13 F14 F15 F16 F17 F18 F19 F20 F21 F22 F23 F24
+	Alt AltLeft AltRight Control ControlLeft ControlRight Shift ShiftLeft ShiftRight Meta MetaLeft MetaRight
+	ArrowDown ArrowLeft ArrowRight ArrowUp Down Left Right Up
+	Backquote Backslash Backspace BracketLeft BracketRight CapsLock Comma ContextMenu Delete End Enter
+	Equal Escape Home Insert IntlBackslash Minus NumLock PageDown PageUp Pause Period PrintScreen Quote
+	ScrollLock Semicolon Slash Space Tab Apostrophe GraveAccent LeftBracket RightBracket Menu
+	Numpad0 Numpad1 Numpad2 Numpad3 Numpad4 Numpad5 Numpad6 Numpad7 Numpad8 Numpad9 NumpadAdd
+	NumpadDecimal NumpadDivide NumpadEnter NumpadEqual NumpadMultiply NumpadSubtract
+	KP0 KP1 KP2 KP3 KP4 KP5 KP6 KP7 KP8 KP9 KPAdd KPDecimal KPDivide KPEnter KPEqual KPMultiply KPSubtract
+`)
+
+// Other common names of ebiten keys
+var ebitenKeyAliases = map[string]string{
+	"leftshift":    "ShiftLeft",
+	"rightshift":   "ShiftRight",
+	"leftcontrol":  "ControlLeft",
+	"rightcontrol": "ControlRight",
+	"leftctrl":     "ControlLeft",
+	"rightctrl":    "ControlRight",
+	"ctrl":         "Control",
+	"leftalt":      "AltLeft",
+	"rightalt":     "AltRight",
+	"esc":          "Escape",
+	"return":       "Enter",
+}
+
+// ebitenKey returns the ebiten.Key constant called name, e.g. "LeftShift" and
+// "KeyShiftLeft" both become "KeyShiftLeft"
+func ebitenKey(name string) (string, error) {
+	lower := strings.ToLower(name)
+	if alias, ok := ebitenKeyAliases[lower]; ok {
+		return "Key" + alias, nil
+	}
+	for _, key := range ebitenKeys {
+		if lower == strings.ToLower(key) || lower == "key"+strings.ToLower(key) {
+			return "Key" + key, nil
+		}
+	}
+	return "", fmt.Errorf("unknown key %q, use the name of an ebiten.Key like Space, W or ShiftLeft", name)
+}
+
+// Method activating an input receiver, one for each player on this machine
+const activateReceiver = "ActivateReceiver"
+
+func printAddActionUsage() {
+	fmt.Println("Usage: bappacreate add action [--key <key>] [--key2 <key>] <Name>")
+	fmt.Println()
+	fmt.Println("Declares an action in actions/actions.go and binds it in main.go. --key")
+	fmt.Println("binds the first receiver, --key2 the second receiver of split-screen")
+	fmt.Println("projects. Keys are ebiten.Key names, with or without the Key prefix.")
+	fmt.Println()
+	fmt.Println("Example:")
+	fmt.Println("  bappacreate add action Dash --key LeftShift --key2 RightShift")
+}
+
+func runAddAction(args []string) error {
+	flags := newFlagSet("add action", printAddActionUsage)
+	key := flags.String("key", "", "key bound to the action for the first receiver, e.g. Space")
+	key2 := flags.String("key2", "", "key bound to the action for the second receiver, in split-screen projects")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageErrorf(flags, "expected one action name, got %d", len(positional))
+	}
+	name, err := exportedName("action", positional[0])
+	if err != nil {
+		return usageErrorf(flags, "%v", err)
+	}
+	if *key2 != "" && *key == "" {
+		return usageErrorf(flags, "--key2 needs a --key for the first receiver")
+	}
+	var keys []string
+	for _, k := range []string{*key, *key2} {
+		if k == "" {
+			continue
+		}
+		constant, err := ebitenKey(k)
+		if err != nil {
+			return usageErrorf(flags, "%v", err)
+		}
+		keys = append(keys, constant)
+	}
+
+	proj, err := findProjectFromWd()
+	if err != nil {
+		return err
+	}
+	changes, err := addAction(proj, name, keys)
+	if err != nil {
+		return err
+	}
+	return changes.apply()
+}
+
+// addAction works out the changes declaring the action name and binding keys[i]
+// for the i-th receiver of every main.go
+func addAction(proj *project, name string, keys []string) (*projectChanges, error) {
+	actionsFile, err := proj.findFile("actions/actions.go")
+	if err != nil {
+		return nil, fmt.Errorf("can't tell where the action goes: %v", err)
+	}
+	dir := filepath.Dir(actionsFile)
+	if err := checkUndeclared(proj, dir, name); err != nil {
+		return nil, err
+	}
+
+	changes := &projectChanges{project: proj}
+	err = declareVar(changes, actionsFile, inputImportPath, func(pkg string) string {
+		return fmt.Sprintf("%s = %s.NewAction()", name, pkg)
+	}, "NewAction")
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return changes, nil
+	}
+
+	importPath, err := proj.importPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	mainFiles, err := proj.findCalls(activateReceiver)
+	if err != nil {
+		return nil, err
+	}
+	if len(mainFiles) == 0 {
+		return nil, fmt.Errorf("no main.go calls %s to bind keys for", activateReceiver)
+	}
+	for _, mainFile := range mainFiles {
+		src, err := bindKeys(mainFile, importPath, name, keys)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", proj.rel(mainFile), err)
+		}
+		changes.update(mainFile, src)
+	}
+	return changes, nil
+}
+
+// bindKeys adds a RegisterKey call for each receiver mainFile activates, after the
+// keys already bound for that receiver
+func bindKeys(mainFile, actionsImportPath, action string, keys []string) ([]byte, error) {
+	file, err := parseGoFile(mainFile)
+	if err != nil {
+		return nil, err
+	}
+	receivers := receiverNames(file)
+	switch {
+	case len(receivers) < len(keys):
+		return nil, fmt.Errorf("only %d receiver is activated, --key2 needs a second one", len(receivers))
+	case len(receivers) > len(keys):
+		return nil, fmt.Errorf("%d receivers are activated, pass --key2 to bind the second one", len(receivers))
+	}
+
+	actionsName := file.importName(actionsImportPath)
+	if actionsName == "" {
+		actionsName = packageNameOf(actionsImportPath)
+	}
+	ebitenName := file.importName(ebitenImportPath)
+	if ebitenName == "" {
+		ebitenName = packageNameOf(ebitenImportPath)
+	}
+
+	for i, receiver := range receivers {
+		after, err := lastBinding(file, receiver)
+		if err != nil {
+			return nil, err
+		}
+		call := fmt.Sprintf("\n%s.RegisterKey(%s.%s, %s.%s)", receiver, ebitenName, keys[i], actionsName, action)
+		src, err := file.insert(file.offset(after.End()), call)
+		if err != nil {
+			return nil, err
+		}
+		if file, err = parseGoSource(mainFile, src); err != nil {
+			return nil, err
+		}
+	}
+
+	src, err := file.addImport(actionsImportPath)
+	if err != nil {
+		return nil, err
+	}
+	if file, err = parseGoSource(mainFile, src); err != nil {
+		return nil, err
+	}
+	return file.addImport(ebitenImportPath)
+}
+
+// receiverNames returns the variables receivers are activated into, in order
+func receiverNames(file *goFile) []string {
+	var names []string
+	for _, call := range file.findCalls(activateReceiver) {
+		block, index := file.statementOf(call)
+		if block == nil {
+			continue
+		}
+		if assign, ok := block.List[index].(*ast.AssignStmt); ok {
+			if ident, ok := assign.Lhs[0].(*ast.Ident); ok && ident.Name != "_" {
+				names = append(names, ident.Name)
+			}
+		}
+	}
+	return names
+}
+
+// lastBinding returns the statement new key bindings of receiver go after: its
+// last RegisterKey call, or else its activation and error check
+func lastBinding(file *goFile, receiver string) (ast.Stmt, error) {
+	var last ast.Stmt
+	ast.Inspect(file.ast, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			if ident, ok := node.Lhs[0].(*ast.Ident); ok && ident.Name == receiver && node.Tok == token.DEFINE && calls(node, activateReceiver) {
+				last = node
+				if block, index := file.statementOf(node); block != nil && index+1 < len(block.List) {
+					if check, ok := block.List[index+1].(*ast.IfStmt); ok && file.text(check.Cond) == "err != nil" {
+						last = check
+					}
+				}
+			}
+		case *ast.ExprStmt:
+			call, ok := node.X.(*ast.CallExpr)
+			if !ok {
+				break
+			}
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "RegisterKey" {
+				if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == receiver {
+					last = node
+				}
+			}
+		}
+		return true
+	})
+	if last == nil {
+		return nil, fmt.Errorf("can't find where %s is activated", receiver)
+	}
+	return last, nil
+}
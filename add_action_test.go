@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddAction(t *testing.T) {
+	addDash := func(keys ...string) func(proj *project) (*projectChanges, error) {
+		return func(proj *project) (*projectChanges, error) {
+			return addAction(proj, "Dash", keys)
+		}
+	}
+	bindingImports := []string{"github.com/me/game/actions", ebitenImportPath}
+	runGeneratorTests(t, []generatorTest{
+		{
+			name: "one receiver",
+			opts: projectOptions{Template: "topdown"},
+			add:  addDash("KeyShiftLeft"),
+			imports: map[string][]string{
+				"actions/actions.go": {inputImportPath},
+				"main.go":            bindingImports,
+			},
+			code: map[string][]string{
+				"actions/actions.go": {"Dash = input.NewAction()"},
+				"main.go":            {"receiver1.RegisterKey(ebiten.KeyShiftLeft, actions.Dash)"},
+			},
+		},
+		{
+			name: "split screen",
+			opts: projectOptions{Genre: "topdown", With: []string{"split"}},
+			add:  addDash("KeyShiftLeft", "KeyShiftRight"),
+			imports: map[string][]string{
+				"actions/actions.go": {inputImportPath},
+				"main.go":            bindingImports,
+			},
+			code: map[string][]string{
+				"actions/actions.go": {"Dash = input.NewAction()"},
+				"main.go": {
+					"receiver1.RegisterKey(ebiten.KeyShiftLeft, actions.Dash)",
+					"receiver2.RegisterKey(ebiten.KeyShiftRight, actions.Dash)",
+				},
+			},
+		},
+		{
+			// Without keys main.go is left alone
+			name:    "no keys",
+			opts:    projectOptions{Template: "topdown"},
+			add:     addDash(),
+			imports: map[string][]string{"actions/actions.go": {inputImportPath}},
+			code:    map[string][]string{"actions/actions.go": {"Dash = input.NewAction()"}},
+		},
+	})
+
+	// Each receiver needs its key
+	proj := generateTestProject(t, projectOptions{Genre: "topdown", With: []string{"split"}})
+	if _, err := addAction(proj, "Dash", []string{"KeyShiftLeft"}); err == nil || !strings.Contains(err.Error(), "pass --key2") {
+		t.Errorf("adding an action with one key to a split-screen project = %v, want an error asking for --key2", err)
+	}
+}
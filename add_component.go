@@ -0,0 +1,409 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const warehouseImportPath = bappaModuleRoot + "/warehouse"
+
+// Packages field types of `add component --fields` may use without components.go
+// importing them first
+var fieldTypePackages = map[string]string{
+	"vector": bappaModuleRoot + "/blueprint/vector",
+}
+
+func printAddComponentUsage() {
+	fmt.Println("Usage: bappacreate add component [--fields Name:type,...] [--composition Name,...] <Name>")
+	fmt.Println()
+	fmt.Println("Creates a component type in components/components.go and registers it")
+	fmt.Println("with warehouse.FactoryNewComponent, e.g. 'Health' declares the type")
+	fmt.Println("Health and the component HealthComponent.")
+	fmt.Println()
+	fmt.Println("Example:")
+	fmt.Println("  bappacreate add component Health --fields \"Current:int,Max:int\" --composition Player")
+}
+
+func printAddTagUsage() {
+	fmt.Println("Usage: bappacreate add tag [--composition Name,...] <Name>")
+	fmt.Println()
+	fmt.Println("Declares a tag in components/tags.go, e.g. 'Enemy' declares EnemyTag.")
+}
+
+func runAddComponent(args []string) error {
+	flags := newFlagSet("add component", printAddComponentUsage)
+	fieldList := flags.String("fields", "", "comma separated Name:type fields of the component, e.g. \"Current:int,Max:int\"")
+	compositionList := flags.String("composition", "", "comma separated compositions in scenes/compositions.go to add the component to, e.g. Player")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageErrorf(flags, "expected one component name, got %d", len(positional))
+	}
+	typeName, err := exportedName("component", strings.TrimSuffix(positional[0], "Component"))
+	if err != nil {
+		return usageErrorf(flags, "%v", err)
+	}
+	fields, err := parseComponentFields(*fieldList)
+	if err != nil {
+		return usageErrorf(flags, "%v", err)
+	}
+
+	proj, err := findProjectFromWd()
+	if err != nil {
+		return err
+	}
+	changes, err := addComponent(proj, typeName, fields, splitList(*compositionList))
+	if err != nil {
+		return err
+	}
+	return changes.apply()
+}
+
+func runAddTag(args []string) error {
+	flags := newFlagSet("add tag", printAddTagUsage)
+	compositionList := flags.String("composition", "", "comma separated compositions in scenes/compositions.go to add the tag to, e.g. Player")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageErrorf(flags, "expected one tag name, got %d", len(positional))
+	}
+	name, err := exportedName("tag", strings.TrimSuffix(positional[0], "Tag"))
+	if err != nil {
+		return usageErrorf(flags, "%v", err)
+	}
+
+	proj, err := findProjectFromWd()
+	if err != nil {
+		return err
+	}
+	changes, err := addTag(proj, name+"Tag", splitList(*compositionList))
+	if err != nil {
+		return err
+	}
+	return changes.apply()
+}
+
+// findProjectFromWd returns the project of the working directory
+func findProjectFromWd() (*project, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return findProject(wd)
+}
+
+// splitList splits a comma separated flag value, dropping empty entries
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// componentField is a field of a generated component type
+type componentField struct {
+	Name string
+	Type ast.Expr
+	Text string // the type as written
+}
+
+// parseComponentFields parses a --fields value like "Current:int,Max:int"
+func parseComponentFields(list string) ([]componentField, error) {
+	var fields []componentField
+	seen := map[string]bool{}
+	for _, entry := range splitList(list) {
+		name, typ, ok := strings.Cut(entry, ":")
+		name, typ = strings.TrimSpace(name), strings.TrimSpace(typ)
+		if !ok || name == "" || typ == "" {
+			return nil, fmt.Errorf("invalid field %q, use Name:type", entry)
+		}
+		name, err := exportedName("field", name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("field %s is listed twice", name)
+		}
+		seen[name] = true
+
+		expr, err := parser.ParseExpr(typ)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type %q for field %s", typ, name)
+		}
+		fields = append(fields, componentField{Name: name, Type: expr, Text: typ})
+	}
+	return fields, nil
+}
+
+// addComponent works out the changes adding the component type typeName to proj
+func addComponent(proj *project, typeName string, fields []componentField, compositions []string) (*projectChanges, error) {
+	componentsFile, err := proj.findFile("components/components.go")
+	if err != nil {
+		return nil, fmt.Errorf("can't tell where the component goes: %v", err)
+	}
+	varName := typeName + "Component"
+	for _, name := range []string{typeName, varName} {
+		if err := checkUndeclared(proj, filepath.Dir(componentsFile), name); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := &projectChanges{project: proj}
+	file, err := changes.parse(componentsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Field types from other packages need their import
+	var imports []string
+	for _, field := range fields {
+		var unknown error
+		ast.Inspect(field.Type, func(node ast.Node) bool {
+			sel, ok := node.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			pkg, ok := sel.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			switch importPath, known := fieldTypePackages[pkg.Name]; {
+			case file.importsName(pkg.Name):
+			case known:
+				imports = append(imports, importPath)
+			default:
+				unknown = fmt.Errorf("field %s: unknown package %s in %s, import it in %s first", field.Name, pkg.Name, field.Text, proj.rel(componentsFile))
+			}
+			return false
+		})
+		if unknown != nil {
+			return nil, unknown
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\ntype %s struct {\n", typeName)
+	for _, field := range fields {
+		fmt.Fprintf(&b, "%s %s\n", field.Name, field.Text)
+	}
+	b.WriteString("}\n")
+	src, err := file.insert(len(file.src), b.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, importPath := range imports {
+		if file, err = parseGoSource(componentsFile, src); err != nil {
+			return nil, err
+		}
+		if src, err = file.addImport(importPath); err != nil {
+			return nil, err
+		}
+	}
+	changes.update(componentsFile, src)
+
+	if err := registerComponent(changes, componentsFile, varName, typeName); err != nil {
+		return nil, err
+	}
+	if err := addToCompositions(proj, changes, filepath.Dir(componentsFile), varName, compositions); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Header of a tags.go created by `add tag`, the way the templates start theirs
+const tagsFileHeader = `package %s
+
+import "github.com/TheBitDrifter/bappa/warehouse"
+
+// Tags help us identify/categorize archetypes/entities when their composition alone isn't enough
+var (
+)
+`
+
+// addTag works out the changes adding the tag varName to proj. Every tag gets an
+// empty type of its own, warehouse tells components apart by their type.
+func addTag(proj *project, varName string, compositions []string) (*projectChanges, error) {
+	componentsFile, err := proj.findFile("components/components.go")
+	if err != nil {
+		return nil, fmt.Errorf("can't tell where the tag goes: %v", err)
+	}
+	dir := filepath.Dir(componentsFile)
+	typeName := unexportedName(varName)
+	for _, name := range []string{varName, typeName} {
+		if err := checkUndeclared(proj, dir, name); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := &projectChanges{project: proj}
+	tagsFile := filepath.Join(dir, "tags.go")
+	if !fileExists(tagsFile) {
+		components, err := parseGoFile(componentsFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := changes.create(tagsFile, []byte(fmt.Sprintf(tagsFileHeader, components.ast.Name.Name))); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := changes.parse(tagsFile)
+	if err != nil {
+		return nil, err
+	}
+	src, err := file.insert(len(file.src), fmt.Sprintf("\ntype %s struct{}\n", typeName))
+	if err != nil {
+		return nil, err
+	}
+	changes.update(tagsFile, src)
+
+	if err := registerComponent(changes, tagsFile, varName, typeName); err != nil {
+		return nil, err
+	}
+	if err := addToCompositions(proj, changes, dir, varName, compositions); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// checkUndeclared fails when the package in dir already declares name
+func checkUndeclared(proj *project, dir, name string) error {
+	file, err := declaredIn(dir, name)
+	if err != nil {
+		return err
+	}
+	if file != "" {
+		return fmt.Errorf("%s is already declared in %s", name, proj.rel(file))
+	}
+	return nil
+}
+
+// registerComponent declares varName as warehouse.FactoryNewComponent[typeArg]()
+// in name, next to the components it already declares
+func registerComponent(changes *projectChanges, name, varName, typeArg string) error {
+	return declareVar(changes, name, warehouseImportPath, func(pkg string) string {
+		return fmt.Sprintf("%s = %s.FactoryNewComponent[%s]()", varName, pkg, typeArg)
+	}, "FactoryNewComponent")
+}
+
+// declareVar adds the var spec returned by spec to name, after the last var
+// declaration calling constructor, or to its empty var block. spec is called with
+// the name name imports importPath as, importPath is imported when needed.
+func declareVar(changes *projectChanges, name, importPath string, spec func(pkg string) string, constructor string) error {
+	file, err := changes.parse(name)
+	if err != nil {
+		return err
+	}
+	pkg := file.importName(importPath)
+	if pkg == "" {
+		pkg = packageNameOf(importPath)
+	}
+	varSpec := spec(pkg)
+
+	var decl *ast.GenDecl
+	for _, d := range file.ast.Decls {
+		if gen, ok := d.(*ast.GenDecl); ok && gen.Tok == token.VAR && (gen.Lparen.IsValid() && len(gen.Specs) == 0 || calls(gen, constructor)) {
+			decl = gen
+		}
+	}
+
+	var src []byte
+	switch {
+	case decl == nil:
+		src, err = file.insert(len(file.src), "\nvar "+varSpec+"\n")
+	case decl.Lparen.IsValid():
+		tokenFile := file.fset.File(decl.Pos())
+		if tokenFile.Line(decl.Rparen) > tokenFile.Line(decl.Lparen) {
+			src, err = file.insert(tokenFile.Offset(tokenFile.LineStart(tokenFile.Line(decl.Rparen))), varSpec+"\n")
+		} else {
+			src, err = file.insert(file.offset(decl.Rparen), "\n"+varSpec+"\n")
+		}
+	default:
+		src, err = file.insert(file.offset(decl.End()), "\nvar "+varSpec)
+	}
+	if err != nil {
+		return err
+	}
+
+	if file, err = parseGoSource(name, src); err != nil {
+		return err
+	}
+	if src, err = file.addImport(importPath); err != nil {
+		return err
+	}
+	changes.update(name, src)
+	return nil
+}
+
+// calls reports whether node calls a function or method called name
+func calls(node ast.Node, name string) bool {
+	found := false
+	ast.Inspect(node, func(node ast.Node) bool {
+		if sel, ok := node.(*ast.SelectorExpr); ok && sel.Sel.Name == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// addToCompositions adds the component varName of the package in componentsDir
+// to each of the composition slices, e.g. Player for PlayerComposition
+func addToCompositions(proj *project, changes *projectChanges, componentsDir, varName string, compositions []string) error {
+	if len(compositions) == 0 {
+		return nil
+	}
+	importPath, err := proj.importPath(componentsDir)
+	if err != nil {
+		return err
+	}
+
+	for _, composition := range compositions {
+		if !strings.HasSuffix(composition, "Composition") {
+			composition += "Composition"
+		}
+		name, err := proj.findVar(composition)
+		if err != nil {
+			return err
+		}
+		file, err := changes.parse(name)
+		if err != nil {
+			return err
+		}
+		list, ok := file.findVar(composition).(*ast.CompositeLit)
+		if !ok {
+			return fmt.Errorf("%s: %s is not a slice literal", proj.rel(name), composition)
+		}
+
+		packageName := file.importName(importPath)
+		if packageName == "" {
+			packageName = filepath.Base(componentsDir)
+		}
+		src, err := file.appendElement(list.Lbrace, list.Rbrace, list.Elts, packageName+"."+varName)
+		if err != nil {
+			return err
+		}
+		if file, err = parseGoSource(name, src); err != nil {
+			return err
+		}
+		if src, err = file.addImport(importPath); err != nil {
+			return err
+		}
+		changes.update(name, src)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddComponent(t *testing.T) {
+	fields, err := parseComponentFields("Current:int,Knockback:vector.Two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGeneratorTests(t, []generatorTest{
+		{
+			name: "to a composition",
+			opts: projectOptions{Template: "topdown"},
+			add: func(proj *project) (*projectChanges, error) {
+				return addComponent(proj, "Health", fields, []string{"Player"})
+			},
+			imports: map[string][]string{
+				"components/components.go": {warehouseImportPath, bappaModuleRoot + "/blueprint/vector"},
+				"scenes/compositions.go":   {"github.com/me/game/components"},
+			},
+			code: map[string][]string{
+				"components/components.go": {"type Health struct { Current int Knockback vector.Two }", "HealthComponent = warehouse.FactoryNewComponent[Health]()"},
+				"scenes/compositions.go":   {"components.HealthComponent"},
+			},
+		},
+	})
+}
+
+func TestAddTag(t *testing.T) {
+	addEnemyTag := func(proj *project) (*projectChanges, error) {
+		return addTag(proj, "EnemyTag", []string{"Prop"})
+	}
+	imports := map[string][]string{
+		"components/tags.go":     {warehouseImportPath},
+		"scenes/compositions.go": {"github.com/me/game/components"},
+	}
+	runGeneratorTests(t, []generatorTest{
+		{
+			name:    "to tags.go",
+			opts:    projectOptions{Template: "topdown"},
+			add:     addEnemyTag,
+			imports: imports,
+			code: map[string][]string{
+				"components/tags.go":     {"BlockTerrainTag", "EnemyTag = warehouse.FactoryNewComponent[enemyTag]()", "type enemyTag struct{}"},
+				"scenes/compositions.go": {"components.EnemyTag"},
+			},
+		},
+		{
+			name: "creating tags.go",
+			opts: projectOptions{Template: "topdown"},
+			prepare: func(t *testing.T, proj *project) {
+				if err := os.Remove(filepath.Join(proj.Root, "components", "tags.go")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			add:     addEnemyTag,
+			imports: imports,
+			code: map[string][]string{
+				"components/tags.go":     {"var ( EnemyTag = warehouse.FactoryNewComponent[enemyTag]() ) type enemyTag struct{}"},
+				"scenes/compositions.go": {"components.EnemyTag"},
+			},
+		},
+	})
+}
+
+// TestAddTagsTypes adds two tags and checks they are registered with types of
+// their own, warehouse can't tell tags of the same type apart
+func TestAddTagsTypes(t *testing.T) {
+	proj := generateTestProject(t, projectOptions{Template: "topdown"})
+	for _, name := range []string{"EnemyTag", "CoinTag"} {
+		changes, err := addTag(proj, name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := changes.apply(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(proj.Root, "components", "tags.go"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeArgs := map[string]string{} // by tag
+	declared := map[string]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.TypeSpec:
+			declared[node.Name.Name] = true
+		case *ast.ValueSpec:
+			if call, ok := node.Values[0].(*ast.CallExpr); ok {
+				if index, ok := call.Fun.(*ast.IndexExpr); ok {
+					typeArgs[node.Names[0].Name] = types.ExprString(index.Index)
+				}
+			}
+		}
+		return true
+	})
+	enemy, coin := typeArgs["EnemyTag"], typeArgs["CoinTag"]
+	if enemy == "" || enemy == coin {
+		t.Errorf("EnemyTag is registered with %q and CoinTag with %q, want distinct types", enemy, coin)
+	}
+	for _, typeName := range []string{enemy, coin} {
+		if !declared[typeName] {
+			t.Errorf("tags.go doesn't declare %s", typeName)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// Method registering a scene with the client, or with the server of a netcode project
+const registerScene = "RegisterScene"
+
+func printAddSceneUsage() {
+	fmt.Println("Usage: bappacreate add scene --width <pixels> --height <pixels> <Name>")
+	fmt.Println()
+	fmt.Println("Creates a scene with an empty plan in scenes/ and registers it in main.go,")
+	fmt.Println("e.g. 'Cave' creates SceneCave in scenes/scene_cave.go. Netcode projects")
+	fmt.Println("register it in every main.go that registers scenes.")
+}
+
+func runAddScene(args []string) error {
+	flags := newFlagSet("add scene", printAddSceneUsage)
+	width := flags.Int("width", 0, "width of the scene in pixels")
+	height := flags.Int("height", 0, "height of the scene in pixels")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageErrorf(flags, "expected one scene name, got %d", len(positional))
+	}
+	if *width <= 0 || *height <= 0 {
+		return usageErrorf(flags, "--width and --height must be set to the scene size in pixels")
+	}
+	name, err := exportedName("scene", positional[0])
+	if err != nil {
+		return usageErrorf(flags, "%v", err)
+	}
+	if trimmed := strings.TrimPrefix(name, "Scene"); trimmed != "" {
+		name = trimmed
+	}
+
+	proj, err := findProjectFromWd()
+	if err != nil {
+		return err
+	}
+	changes, err := addScene(proj, newSceneNames(name), *width, *height)
+	if err != nil {
+		return err
+	}
+	return changes.apply()
+}
+
+// sceneNames are the identifiers of a scene, the way the templates name scene one
+type sceneNames struct {
+	Var   string // SceneCave
+	Const string // SCENE_CAVE_NAME
+	Plan  string // sceneCavePlan
+	File  string // scene_cave.go
+	Title string // Scene Cave, for comments and log messages
+	Name  string // scene cave, the name the scene is registered under
+}
+
+func newSceneNames(name string) sceneNames {
+	snake := snakeCase("Scene" + name)
+	title := sceneTitle("Scene" + name)
+	return sceneNames{
+		Var:   "Scene" + name,
+		Const: strings.ToUpper(snake) + "_NAME",
+		Plan:  "scene" + name + "Plan",
+		File:  snake + ".go",
+		Title: title,
+		Name:  strings.ToLower(title),
+	}
+}
+
+// sceneTitle returns the words of a scene variable, e.g. "SceneOne" becomes "Scene One"
+func sceneTitle(varName string) string {
+	words := strings.Split(snakeCase(varName), "_")
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// addScene works out the changes adding the scene to proj
+func addScene(proj *project, scene sceneNames, width, height int) (*projectChanges, error) {
+	sceneFile, err := proj.findFile("scenes/scene.go")
+	if err != nil {
+		return nil, fmt.Errorf("can't tell where the scene goes: %v", err)
+	}
+	dir := filepath.Dir(sceneFile)
+	for _, name := range []string{scene.Var, scene.Const, scene.Plan} {
+		if err := checkUndeclared(proj, dir, name); err != nil {
+			return nil, err
+		}
+	}
+
+	scenes, err := parseGoFile(sceneFile)
+	if err != nil {
+		return nil, err
+	}
+	source, err := sceneSource(scenes.ast.Name.Name, scene, width, height)
+	if err != nil {
+		return nil, err
+	}
+	changes := &projectChanges{project: proj}
+	if err := changes.create(filepath.Join(dir, scene.File), source); err != nil {
+		return nil, err
+	}
+
+	mainFiles, err := proj.findCalls(registerScene)
+	if err != nil {
+		return nil, err
+	}
+	if len(mainFiles) == 0 {
+		return nil, fmt.Errorf("no main.go calls %s", registerScene)
+	}
+	for _, mainFile := range mainFiles {
+		file, err := parseGoFile(mainFile)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := registerSceneLike(file, scene)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", proj.rel(mainFile), err)
+		}
+		changes.update(mainFile, updated)
+	}
+	return changes, nil
+}
+
+// sceneSource returns the Go file of a new scene
+func sceneSource(packageName string, scene sceneNames, width, height int) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n\nimport %q\n\n", packageName, warehouseImportPath)
+	fmt.Fprintf(&b, "const %s = %q\n\n", scene.Const, scene.Name)
+	fmt.Fprintf(&b, "var %s = Scene{\nName: %s,\nPlan: %s,\nWidth: %d,\nHeight: %d,\n}\n\n", scene.Var, scene.Const, scene.Plan, width, height)
+	fmt.Fprintf(&b, "func %s(width, height int, sto warehouse.Storage) error {\n", scene.Plan)
+	b.WriteString("// Create the entities the scene starts with here, e.g. with the helpers in helpers.go\n")
+	b.WriteString("return nil\n}\n")
+	return format.Source([]byte(b.String()))
+}
+
+// registerSceneLike registers the scene after the last scene the file registers,
+// copying that registration with its comment, log line and error check
+func registerSceneLike(file *goFile, scene sceneNames) ([]byte, error) {
+	calls := file.findCalls(registerScene)
+	call := calls[len(calls)-1]
+
+	// The first argument, e.g. scenes.SceneOne.Name, tells which scene is registered
+	var previous *ast.SelectorExpr
+	if len(call.Args) > 0 {
+		if name, ok := call.Args[0].(*ast.SelectorExpr); ok {
+			previous, _ = name.X.(*ast.SelectorExpr)
+		}
+	}
+	if previous == nil {
+		return nil, fmt.Errorf("can't tell which scene %s registers", file.text(call))
+	}
+	pkg, ok := previous.X.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("can't tell which scene %s registers", file.text(call))
+	}
+	previousTitle := sceneTitle(previous.Sel.Name)
+
+	// The statement making the call, in the block of main
+	block, index := file.statementOf(call)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not called in a function body", registerScene)
+	}
+
+	stmt := block.List[index]
+	first, last := stmt, stmt
+	if index > 0 && mentionsScene(file, block.List[index-1], pkg.Name+"."+previous.Sel.Name, previousTitle) {
+		first = block.List[index-1]
+	}
+	if index+1 < len(block.List) {
+		if check, ok := block.List[index+1].(*ast.IfStmt); ok && file.text(check.Cond) == "err != nil" {
+			last = check
+		}
+	}
+
+	start := file.offset(first.Pos())
+	if comment := commentAbove(file, first); comment != nil {
+		start = file.offset(comment.Pos())
+	}
+	text := string(file.src[start:file.offset(last.End())])
+
+	// err := in the first registration becomes err =
+	if assign, ok := stmt.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+		define := file.offset(assign.TokPos) - start
+		text = text[:define] + "=" + text[define+len(":="):]
+	}
+
+	text = strings.ReplaceAll(text, pkg.Name+"."+previous.Sel.Name+".", pkg.Name+"."+scene.Var+".")
+	text = strings.ReplaceAll(text, previousTitle, scene.Title)
+	text = strings.ReplaceAll(text, strings.ToLower(previousTitle), scene.Name)
+	return file.insert(file.offset(last.End()), "\n\n"+text)
+}
+
+// mentionsScene reports whether stmt is a call mentioning the scene, like a log line
+func mentionsScene(file *goFile, stmt ast.Stmt, selector, title string) bool {
+	expr, ok := stmt.(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	if _, ok := expr.X.(*ast.CallExpr); !ok {
+		return false
+	}
+	text := file.text(expr)
+	return strings.Contains(text, selector+".") || strings.Contains(strings.ToLower(text), strings.ToLower(title))
+}
+
+// commentAbove returns the comment on the lines right above node, if any
+func commentAbove(file *goFile, node ast.Node) *ast.CommentGroup {
+	tokenFile := file.fset.File(node.Pos())
+	line := tokenFile.Line(node.Pos())
+	for _, group := range file.ast.Comments {
+		if tokenFile.Line(group.End()) == line-1 {
+			return group
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestAddScene(t *testing.T) {
+	addCave := func(proj *project) (*projectChanges, error) {
+		return addScene(proj, newSceneNames("Cave"), 640, 360)
+	}
+	runGeneratorTests(t, []generatorTest{
+		{
+			name: "single module",
+			opts: projectOptions{Template: "topdown"},
+			add:  addCave,
+			imports: map[string][]string{
+				"scenes/scene_cave.go": {warehouseImportPath},
+				"main.go":              {"github.com/me/game/scenes"},
+			},
+			code: map[string][]string{
+				"scenes/scene_cave.go": {`const SCENE_CAVE_NAME = "scene cave"`, "Width: 640, Height: 360,"},
+				"main.go":              {"// Register scene cave err = client.RegisterScene( scenes.SceneCave.Name, scenes.SceneCave.Width,"},
+			},
+		},
+		{
+			// Every main package registering scenes gets it
+			name: "workspace",
+			opts: projectOptions{Template: "platformer-netcode"},
+			add:  addCave,
+			imports: map[string][]string{
+				"shared/scenes/scene_cave.go": {warehouseImportPath},
+				"client/main.go":              {"github.com/me/game/shared/scenes"},
+				"server/main.go":              {"github.com/me/game/shared/scenes"},
+				"standalone/main.go":          {"github.com/me/game/shared/scenes"},
+			},
+			code: map[string][]string{
+				"client/main.go":     {"scenes.SceneCave.Name"},
+				"server/main.go":     {"scenes.SceneCave.Name"},
+				"standalone/main.go": {"scenes.SceneCave.Name"},
+			},
+		},
+	})
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"path/filepath"
+	"strings"
+)
+
+// systemKind is a kind of system `add system --kind` generates
+type systemKind struct {
+	List    string // slice of default systems locating the package the system goes in
+	Global  bool   // registered in main.go with RegisterGlobalClientSystem instead of added to List
+	Import  string // package of the system interface
+	Method  string // method implementing the interface
+	Returns bool   // the method returns an error
+}
+
+const coldbrewImportPath = bappaModuleRoot + "/coldbrew"
+
+// Kinds of systems, keyed by --kind
+var systemKinds = map[string]systemKind{
+	"core": {
+		List:    "DefaultCoreSystems",
+		Import:  bappaModuleRoot + "/blueprint",
+		Method:  "Run(scene blueprint.Scene, dt float64) error",
+		Returns: true,
+	},
+	"client": {
+		List:    "DefaultClientSystems",
+		Import:  coldbrewImportPath,
+		Method:  "Run(cli coldbrew.LocalClient, scene coldbrew.Scene) error",
+		Returns: true,
+	},
+	"render": {
+		List:   "DefaultRenderSystems",
+		Import: coldbrewImportPath,
+		Method: "Render(scene coldbrew.Scene, screen coldbrew.Screen, cli coldbrew.LocalClient)",
+	},
+	"global": {
+		List:    "DefaultClientSystems",
+		Global:  true,
+		Import:  coldbrewImportPath,
+		Method:  "Run(cli coldbrew.Client) error",
+		Returns: true,
+	},
+}
+
+// Method of the client global client systems are registered with
+const registerGlobalClientSystem = "RegisterGlobalClientSystem"
+
+func printAddSystemUsage() {
+	fmt.Println("Usage: bappacreate add system --kind core|client|render|global <Name>")
+	fmt.Println()
+	fmt.Println("Creates a system and registers it with the project:")
+	fmt.Println("  core    a blueprint.CoreSystem, added to DefaultCoreSystems")
+	fmt.Println("  client  a coldbrew.ClientSystem, added to DefaultClientSystems")
+	fmt.Println("  render  a coldbrew.RenderSystem, added to DefaultRenderSystems")
+	fmt.Println("  global  a global client system, registered in main.go")
+	fmt.Println()
+	fmt.Println("The name gets a System suffix unless it ends in System or Renderer,")
+	fmt.Println("e.g. 'Dash' creates DashSystem in dash_system.go.")
+}
+
+func runAddSystem(args []string) error {
+	flags := newFlagSet("add system", printAddSystemUsage)
+	kindName := flags.String("kind", "", "kind of system: core, client, render or global")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageErrorf(flags, "expected one system name, got %d", len(positional))
+	}
+	kind, ok := systemKinds[*kindName]
+	if !ok {
+		if *kindName == "" {
+			return usageErrorf(flags, "missing --kind")
+		}
+		return usageErrorf(flags, "unknown kind %q, use core, client, render or global", *kindName)
+	}
+	typeName, err := systemTypeName(positional[0])
+	if err != nil {
+		return usageErrorf(flags, "%v", err)
+	}
+
+	proj, err := findProjectFromWd()
+	if err != nil {
+		return err
+	}
+	changes, err := addSystem(proj, kind, typeName)
+	if err != nil {
+		return err
+	}
+	if err := changes.apply(); err != nil {
+		return err
+	}
+	if *kindName == "core" {
+		fmt.Printf("Core systems run in the order of %s, move %s{} if it has to run earlier.\n", kind.List, typeName)
+	}
+	return nil
+}
+
+// addSystem works out the changes adding the system typeName of kind to proj
+func addSystem(proj *project, kind systemKind, typeName string) (*projectChanges, error) {
+	listFile, err := proj.findVar(kind.List)
+	if err != nil {
+		return nil, fmt.Errorf("can't tell where the system goes: %v", err)
+	}
+	common, err := parseGoFile(listFile)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &projectChanges{project: proj}
+	dir := filepath.Dir(listFile)
+	source, err := systemSource(common.ast.Name.Name, kind, typeName)
+	if err != nil {
+		return nil, err
+	}
+	if err := changes.create(filepath.Join(dir, snakeCase(typeName)+".go"), source); err != nil {
+		return nil, err
+	}
+
+	if !kind.Global {
+		list, ok := common.findVar(kind.List).(*ast.CompositeLit)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s is not a slice literal", proj.rel(listFile), kind.List)
+		}
+		updated, err := common.appendElement(list.Lbrace, list.Rbrace, list.Elts, typeName+"{}")
+		if err != nil {
+			return nil, err
+		}
+		changes.update(listFile, updated)
+		return changes, nil
+	}
+
+	// Global systems are registered by every main package registering global client systems
+	mainFiles, err := proj.findCalls(registerGlobalClientSystem)
+	if err != nil {
+		return nil, err
+	}
+	if len(mainFiles) == 0 {
+		return nil, fmt.Errorf("no main.go calls %s", registerGlobalClientSystem)
+	}
+	importPath, err := proj.importPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, mainFile := range mainFiles {
+		updated, err := registerGlobalSystem(mainFile, importPath, common.ast.Name.Name, typeName)
+		if err != nil {
+			return nil, err
+		}
+		changes.update(mainFile, updated)
+	}
+	return changes, nil
+}
+
+// registerGlobalSystem adds the system to the RegisterGlobalClientSystem call of
+// mainFile, importing its package when needed
+func registerGlobalSystem(mainFile, importPath, packageName, typeName string) ([]byte, error) {
+	file, err := parseGoFile(mainFile)
+	if err != nil {
+		return nil, err
+	}
+	// An import under another name is used as is
+	if name := file.importName(importPath); name != "" {
+		packageName = name
+	}
+
+	call := file.findCalls(registerGlobalClientSystem)[0]
+	updated, err := file.appendElement(call.Lparen, call.Rparen, call.Args, packageName+"."+typeName+"{}")
+	if err != nil {
+		return nil, err
+	}
+	if file, err = parseGoSource(mainFile, updated); err != nil {
+		return nil, err
+	}
+	return file.addImport(importPath)
+}
+
+// systemSource returns the Go file of a new system
+func systemSource(packageName string, kind systemKind, typeName string) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n\nimport %q\n\n", packageName, kind.Import)
+	fmt.Fprintf(&b, "type %s struct{}\n\n", typeName)
+	fmt.Fprintf(&b, "func (%s) %s {\n", typeName, kind.Method)
+	if kind.Returns {
+		b.WriteString("return nil\n")
+	}
+	b.WriteString("}\n")
+	return format.Source([]byte(b.String()))
+}
+
+// systemTypeName returns the type of the system called name, e.g. "dash" becomes
+// "DashSystem"
+func systemTypeName(name string) (string, error) {
+	name, err := exportedName("system", name)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(name, "System") && !strings.HasSuffix(name, "Renderer") {
+		name += "System"
+	}
+	return name, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestAddSystem(t *testing.T) {
+	add := func(kind, typeName string) func(proj *project) (*projectChanges, error) {
+		return func(proj *project) (*projectChanges, error) {
+			return addSystem(proj, systemKinds[kind], typeName)
+		}
+	}
+	runGeneratorTests(t, []generatorTest{
+		{
+			name: "core",
+			opts: projectOptions{Template: "topdown"},
+			add:  add("core", "DashSystem"),
+			imports: map[string][]string{
+				"coresystems/dash_system.go": {systemKinds["core"].Import},
+				"coresystems/common.go":      nil,
+			},
+			code: map[string][]string{"coresystems/common.go": {"DashSystem{}"}},
+		},
+		{
+			name: "client",
+			opts: projectOptions{Template: "topdown"},
+			add:  add("client", "DashSystem"),
+			imports: map[string][]string{
+				"clientsystems/dash_system.go": {systemKinds["client"].Import},
+				"clientsystems/common.go":      nil,
+			},
+			code: map[string][]string{"clientsystems/common.go": {"DashSystem{}"}},
+		},
+		{
+			name: "render",
+			opts: projectOptions{Template: "topdown"},
+			add:  add("render", "DashSystem"),
+			imports: map[string][]string{
+				"rendersystems/dash_system.go": {systemKinds["render"].Import},
+				"rendersystems/common.go":      nil,
+			},
+			code: map[string][]string{"rendersystems/common.go": {"DashSystem{}"}},
+		},
+		{
+			name: "global",
+			opts: projectOptions{Template: "topdown"},
+			add:  add("global", "DashSystem"),
+			imports: map[string][]string{
+				"clientsystems/dash_system.go": {systemKinds["global"].Import},
+				"main.go":                      {"github.com/me/game/clientsystems"},
+			},
+			code: map[string][]string{"main.go": {"&coldbrew_clientsystems.CameraSceneAssignerSystem{}, clientsystems.DashSystem{}, )"}},
+		},
+		{
+			// Every main package registering global systems gets it
+			name: "global in a workspace",
+			opts: projectOptions{Template: "platformer-netcode"},
+			add:  add("global", "LobbySystem"),
+			imports: map[string][]string{
+				"sharedclient/clientsystems/lobby_system.go": {systemKinds["global"].Import},
+				"client/main.go":     {"github.com/me/game/sharedclient/clientsystems"},
+				"standalone/main.go": {"github.com/me/game/sharedclient/clientsystems"},
+			},
+		},
+	})
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+)
+
+// version is the bappacreate release; it can be set at build time with
+// -ldflags "-X main.version=v1.2.3" and otherwise falls back to the module
+// version recorded by `go install`.
+var version = ""
+
+// command describes a single bappacreate subcommand
+type command struct {
+	Name    string
+	Summary string
+	Run     func(args []string) error
+}
+
+// usageError marks errors caused by bad command line input. The usage text
+// has already been printed when one of these is returned.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+// commandList returns every registered subcommand in the order they are shown in help
+func commandList() []*command {
+	return []*command{
+		{Name: "new", Summary: "Create a new Bappa game project from a template", Run: runNew},
+		{Name: "list", Summary: "List the available templates", Run: runList},
+		{Name: "add", Summary: "Add code (systems, components, ...) to an existing project", Run: runAdd},
+		{Name: "upgrade", Summary: "Bring template changes into a generated project", Run: runUpgrade},
+		{Name: "doctor", Summary: "Check a template or project for broken references", Run: runDoctor},
+		{Name: "version", Summary: "Print the bappacreate version", Run: runVersion},
+		{Name: "help", Summary: "Show help for a command", Run: runHelp},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commandList() {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// runCLI dispatches args (without the program name) and returns the process exit code
+func runCLI(args []string) int {
+	if len(args) == 0 {
+		// In a terminal, a bare bappacreate walks through creating a project
+		if isInteractive() {
+			return exitCode(runNew(nil))
+		}
+		printMainUsage()
+		return 1
+	}
+
+	name := args[0]
+	switch name {
+	case "-h", "-help", "--help":
+		printMainUsage()
+		return 0
+	case "-version", "--version":
+		name = "version"
+	}
+
+	cmd := findCommand(name)
+	cmdArgs := args[1:]
+
+	// Keep the original `bappacreate username/project [--template x]` form working
+	if cmd == nil && !strings.HasPrefix(name, "-") && strings.Contains(name, "/") {
+		cmd = findCommand("new")
+		cmdArgs = args
+	}
+
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+		printMainUsage()
+		return 2
+	}
+
+	return exitCode(cmd.Run(cmdArgs))
+}
+
+// exitCode reports err to the user and converts it into a process exit code
+func exitCode(err error) int {
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &usageErr):
+		return 2
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+}
+
+func printMainUsage() {
+	fmt.Println("Bappa Game Template Generator")
+	fmt.Println("===============================")
+	fmt.Println("Usage: bappacreate <command> [flags] [arguments]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, cmd := range commandList() {
+		fmt.Printf("  %-8s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Println()
+	fmt.Println("Run 'bappacreate <command> --help' for details about a command.")
+}
+
+// newFlagSet creates a flag set whose --help output prints printHelp followed by the flag defaults
+func newFlagSet(name string, printHelp func()) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		printHelp()
+		hasFlags := false
+		flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(flags.Output())
+			fmt.Fprintln(flags.Output(), "Flags:")
+			flags.PrintDefaults()
+		}
+	}
+	flags.SetOutput(os.Stdout)
+	return flags
+}
+
+// parseFlags parses args allowing flags before and after positional arguments,
+// returning the positional arguments in order
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &usageError{err}
+		}
+
+		rest := flags.Args()
+		consumed := len(args) - len(rest)
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// usageErrorf prints an error followed by the flag set's usage and returns a usageError
+func usageErrorf(flags *flag.FlagSet, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+	flags.Usage()
+	return &usageError{err}
+}
+
+func runNew(args []string) error {
+	flags := newFlagSet("new", printUsage)
+	opts := projectOptions{}
+	flags.StringVar(&opts.Template, "template", "", "name of the template to generate from, see 'bappacreate list' (default \""+defaultTemplate+"\", or the only template of --template-dir/--template-repo)")
+	flags.StringVar(&opts.Genre, "genre", "", "base template to add --with features to, e.g. platformer or topdown (instead of --template)")
+	with := flags.String("with", "", "comma separated features to add to --genre, e.g. split,ldtk, see 'bappacreate list'")
+	addTemplateFlags(flags, &opts.Templates)
+	flags.StringVar(&opts.Module, "module", "", "full Go module path of the project, e.g. git.example.com/games/my-game")
+	flags.StringVar(&opts.Author, "author", "", "author name used in the generated files (default the module path's GitHub or GitLab user)")
+	flags.StringVar(&opts.Title, "title", "", "window title of the game (default derived from the project name)")
+	resolution := flags.String("resolution", "", "window size as <width>x<height>, e.g. 1280x720 (default the template's)")
+	yes := flags.Bool("yes", false, "never prompt; without it, running 'new' without a project name in a terminal asks for the settings")
+	flags.BoolVar(&opts.DryRun, "dry-run", false, "print the files that would be created without writing anything")
+	flags.BoolVar(&opts.JSON, "json", false, "with --dry-run, print the plan as JSON")
+	flags.BoolVar(&opts.Force, "force", false, "generate into an existing, non-empty directory, overwriting files")
+	flags.BoolVar(&opts.Merge, "merge", false, "generate into an existing directory, only writing missing files and reporting conflicts")
+	flags.BoolVar(&opts.Deps.Offline, "offline", false, "write go.mod and go.sum with pinned bappa versions instead of running 'go get'")
+	flags.BoolVar(&opts.NoHooks, "no-hooks", false, "don't run the post-generate hooks of the template and of the user config file")
+	flags.BoolVar(&opts.AllowHooks, "allow-hooks", false, "run the shell command hooks of a --template-dir or --template-repo template")
+	flags.StringVar(&opts.Deps.BappaVersion, "bappa-version", "", "version, tag or pseudo-version to use for every bappa module (default latest, or the pinned version with --offline)")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(positional) > 1:
+		return usageErrorf(flags, "expected one project name, got %d", len(positional))
+	case len(positional) == 0 && opts.Module == "" && (*yes || !isInteractive()):
+		return usageErrorf(flags, "missing project name")
+	case len(positional) == 1:
+		opts.Name = positional[0]
+	}
+	if *resolution != "" {
+		r, err := parseResolution(*resolution)
+		if err != nil {
+			return usageErrorf(flags, "%v", err)
+		}
+		opts.Resolution = &r
+	}
+	if *with != "" {
+		for _, feature := range strings.Split(*with, ",") {
+			if feature = strings.TrimSpace(feature); feature != "" {
+				opts.With = append(opts.With, feature)
+			}
+		}
+	}
+	if len(opts.With) > 0 && opts.Genre == "" {
+		return usageErrorf(flags, "--with needs a --genre")
+	}
+	if opts.Genre != "" && opts.Template != "" {
+		return usageErrorf(flags, "--genre and --template cannot be used together")
+	}
+	if opts.JSON && !opts.DryRun {
+		return usageErrorf(flags, "--json can only be used with --dry-run")
+	}
+	if opts.Force && opts.Merge {
+		return usageErrorf(flags, "--force and --merge cannot be used together")
+	}
+	if err := opts.Deps.validate(); err != nil {
+		return usageErrorf(flags, "%v", err)
+	}
+
+	// Without a project name the wizard asks for the settings the flags didn't set
+	if opts.Name == "" && opts.Module == "" {
+		return runWizard(opts)
+	}
+	return createProject(opts)
+}
+
+// addTemplateFlags adds the flags that pick where templates are read from
+func addTemplateFlags(flags *flag.FlagSet, opts *templateOptions) {
+	flags.StringVar(&opts.Dir, "template-dir", "", "read templates from this directory instead of the built-in ones (a template, or a directory of templates)")
+	flags.StringVar(&opts.Repo, "template-repo", "", "read templates from a git repository, as <url>[@<ref>] (file:// URLs work offline)")
+}
+
+// listedTemplate is a template as printed by `list --json`
+type listedTemplate struct {
+	*TemplateManifest
+	Source string `json:"source"`
+}
+
+func runList(args []string) error {
+	flags := newFlagSet("list", func() {
+		fmt.Println("Usage: bappacreate list [--json] [--template-dir <dir> | --template-repo <url>[@<ref>]]")
+		fmt.Println()
+		fmt.Println("Lists the templates that can be passed to 'bappacreate new --template', and the")
+		fmt.Println("features that can be added to a genre with 'bappacreate new --genre <genre> --with <feature>,...'.")
+	})
+	asJSON := flags.Bool("json", false, "print the template manifests as JSON")
+	var templateOpts templateOptions
+	addTemplateFlags(flags, &templateOpts)
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usageErrorf(flags, "unexpected arguments: %s", strings.Join(positional, " "))
+	}
+
+	templates, cleanup, err := loadTemplates(templateOpts)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if *asJSON {
+		listed := make([]listedTemplate, len(templates))
+		for i, template := range templates {
+			listed[i] = listedTemplate{TemplateManifest: template.Manifest, Source: sourceName(template.Source)}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listed)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESCRIPTION\tTAGS\tRESOLUTION\tSOURCE")
+	for _, template := range templates {
+		manifest := template.Manifest
+		name := manifest.Name
+		if name == defaultTemplate {
+			name += " (default)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, manifest.Description, strings.Join(manifest.Tags, ","), manifest.Resolution, sourceName(template.Source))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	layers, err := loadLayers()
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FEATURE\tGENRES\tDESCRIPTION")
+	for _, layer := range layers {
+		name := layer.Name
+		if layer.Default {
+			name += " (default)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, strings.Join(layer.genreNames(), ","), layer.Description)
+	}
+	return w.Flush()
+}
+
+// generator is a code generator run by `bappacreate add <name>`
+type generator struct {
+	Name    string
+	Summary string
+	Run     func(args []string) error
+}
+
+// generatorList returns the generators available to `bappacreate add`
+func generatorList() []*generator {
+	return []*generator{
+		{Name: "system", Summary: "Add a core, client, render or global system", Run: runAddSystem},
+		{Name: "component", Summary: "Add a component type and register it", Run: runAddComponent},
+		{Name: "tag", Summary: "Add a tag component", Run: runAddTag},
+		{Name: "scene", Summary: "Add a scene and register it in main.go", Run: runAddScene},
+		{Name: "action", Summary: "Add an input action and bind keys to it", Run: runAddAction},
+	}
+}
+
+func printAddUsage() {
+	fmt.Println("Usage: bappacreate add <generator> [flags] [arguments]")
+	fmt.Println()
+	fmt.Println("Adds generated code to the Bappa project in the current directory.")
+	fmt.Println()
+	generators := generatorList()
+	if len(generators) == 0 {
+		fmt.Println("No generators are available yet.")
+		return
+	}
+	fmt.Println("Generators:")
+	for _, gen := range generators {
+		fmt.Printf("  %-10s %s\n", gen.Name, gen.Summary)
+	}
+}
+
+func runAdd(args []string) error {
+	flags := newFlagSet("add", printAddUsage)
+
+	// Only the generator name is parsed here, its flags belong to the generator
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{err}
+	}
+	if flags.NArg() == 0 {
+		return usageErrorf(flags, "missing generator name")
+	}
+
+	name := flags.Arg(0)
+	for _, gen := range generatorList() {
+		if gen.Name == name {
+			return gen.Run(flags.Args()[1:])
+		}
+	}
+	return usageErrorf(flags, "unknown generator %q", name)
+}
+
+func runVersion(args []string) error {
+	flags := newFlagSet("version", func() {
+		fmt.Println("Usage: bappacreate version")
+	})
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usageErrorf(flags, "unexpected arguments: %s", strings.Join(positional, " "))
+	}
+
+	fmt.Println("bappacreate", buildVersion())
+	return nil
+}
+
+// buildVersion returns the linker-provided version, the module version, or "devel"
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+func runHelp(args []string) error {
+	if len(args) == 0 {
+		printMainUsage()
+		return nil
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil || cmd.Name == "help" {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", args[0])
+		printMainUsage()
+		return &usageError{fmt.Errorf("unknown command %q", args[0])}
+	}
+	return cmd.Run(append(args[1:], "--help"))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"slices"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		template   string
+		dryRun     bool
+	}{
+		{args: nil},
+		{args: []string{"me/game"}, positional: []string{"me/game"}},
+		{args: []string{"--template", "platformer", "me/game"}, positional: []string{"me/game"}, template: "platformer"},
+		{args: []string{"me/game", "--template", "platformer"}, positional: []string{"me/game"}, template: "platformer"},
+		{args: []string{"me/game", "--dry-run", "other", "--template=sandbox"}, positional: []string{"me/game", "other"}, template: "sandbox", dryRun: true},
+		{args: []string{"--dry-run", "--", "--template", "me/game"}, positional: []string{"--template", "me/game"}, dryRun: true},
+		{args: []string{"me/game", "--", "-x"}, positional: []string{"me/game", "-x"}},
+	}
+	for _, tt := range tests {
+		flags := newFlagSet("test", func() {})
+		template := flags.String("template", "", "")
+		dryRun := flags.Bool("dry-run", false, "")
+
+		positional, err := parseFlags(flags, tt.args)
+		if err != nil {
+			t.Errorf("parseFlags(%q): %v", tt.args, err)
+			continue
+		}
+		if !slices.Equal(positional, tt.positional) || *template != tt.template || *dryRun != tt.dryRun {
+			t.Errorf("parseFlags(%q) = %q, template %q, dry run %v; want %q, %q, %v", tt.args, positional, *template, *dryRun, tt.positional, tt.template, tt.dryRun)
+		}
+	}
+}
+
+func TestParseFlagsErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		help bool
+	}{
+		{args: []string{"--unknown"}},
+		{args: []string{"me/game", "--template"}},
+		{args: []string{"me/game", "--dry-run=maybe"}},
+		{args: []string{"--help"}, help: true},
+		{args: []string{"me/game", "-h"}, help: true},
+	}
+	for _, tt := range tests {
+		flags := newFlagSet("test", func() {})
+		flags.String("template", "", "")
+		flags.Bool("dry-run", false, "")
+
+		_, err := parseFlags(flags, tt.args)
+		var usageErr *usageError
+		switch {
+		case tt.help && !errors.Is(err, flag.ErrHelp):
+			t.Errorf("parseFlags(%q) = %v, want flag.ErrHelp", tt.args, err)
+		case !tt.help && !errors.As(err, &usageErr):
+			t.Errorf("parseFlags(%q) = %v, want a usage error", tt.args, err)
+		}
+	}
+}
+
+func TestRunCLIExitCodes(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{args: []string{"--help"}, code: 0},
+		{args: []string{"new", "--help"}, code: 0},
+		{args: []string{"version"}, code: 0},
+		{args: []string{"bogus"}, code: 2},
+		{args: []string{"new", "--bogus", "me/game"}, code: 2},
+		{args: []string{"new", "me/game", "other/game"}, code: 2},
+		{args: []string{"new", "--yes"}, code: 2},
+		{args: []string{"new", "--json", "me/game"}, code: 2},
+		{args: []string{"new", "--dry-run", "--template", "missing", "me/game"}, code: 1},
+		{args: []string{"new", "--dry-run", "--offline", "me/game"}, code: 0},
+		// The form from before subcommands, with flags after the project name
+		{args: []string{"me/game", "--dry-run", "--offline", "--template", "platformer"}, code: 0},
+		{args: []string{"me/game", "--bogus"}, code: 2},
+	}
+	for _, tt := range tests {
+		if code := runCLI(tt.args); code != tt.code {
+			t.Errorf("runCLI(%q) = %d, want %d", tt.args, code, tt.code)
+		}
+	}
+}
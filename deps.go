@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	goversion "go/version"
+	"io/fs"
+	"path"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// bappacreate's own module files. The templates are built as part of this
+// module, so its requirements are exactly what a generated project needs.
+//
+//go:embed go.mod go.sum
+var bappacreateModule embed.FS
+
+// Requirements of bappacreate itself that generated projects don't need
+var generatorOnlyModules = map[string]bool{
+	"golang.org/x/mod":   true,
+	"golang.org/x/tools": true,
+}
+
+// Direct requirements of bappacreate that are only indirect dependencies of the templates
+var templateIndirectModules = map[string]bool{
+	"golang.org/x/text": true,
+}
+
+// dependencyOptions controls how a generated project gets its dependencies
+type dependencyOptions struct {
+	Offline      bool   // write go.mod and go.sum instead of running go commands
+	BappaVersion string // version used for every bappa module, "" means latest (or pinned when offline)
+}
+
+func (d dependencyOptions) validate() error {
+	if d.BappaVersion == "" {
+		return nil
+	}
+	if d.Offline && !semver.IsValid(d.BappaVersion) {
+		return fmt.Errorf("--bappa-version %q must be a semantic version or pseudo-version when used with --offline", d.BappaVersion)
+	}
+	if strings.ContainsAny(d.BappaVersion, " @") {
+		return fmt.Errorf("invalid --bappa-version %q", d.BappaVersion)
+	}
+	return nil
+}
+
+// version returns the version query used with `go get`
+func (d dependencyOptions) version() string {
+	if d.BappaVersion == "" {
+		return "latest"
+	}
+	return d.BappaVersion
+}
+
+func isBappaModule(modulePath string) bool {
+	return strings.HasPrefix(modulePath, bappaModuleRoot+"/")
+}
+
+// offlineGoMod builds a go.mod for modulePath that requires everything bappacreate
+// itself requires, pinned to the same versions unless deps overrides the bappa version
+func offlineGoMod(modulePath string, modules []string, deps dependencyOptions) ([]byte, error) {
+	data, err := bappacreateModule.ReadFile("go.mod")
+	if err != nil {
+		return nil, err
+	}
+	own, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	goMod := &modfile.File{}
+	if err := goMod.AddModuleStmt(modulePath); err != nil {
+		return nil, err
+	}
+	if own.Go != nil {
+		if err := goMod.AddGoStmt(own.Go.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	required := map[string]bool{}
+	for _, req := range own.Require {
+		if generatorOnlyModules[req.Mod.Path] {
+			continue
+		}
+		version := req.Mod.Version
+		if isBappaModule(req.Mod.Path) && deps.BappaVersion != "" {
+			version = deps.BappaVersion
+		}
+		goMod.AddNewRequire(req.Mod.Path, version, req.Indirect || templateIndirectModules[req.Mod.Path])
+		required[req.Mod.Path] = true
+	}
+
+	for _, module := range modules {
+		if required[module] {
+			continue
+		}
+		if deps.BappaVersion == "" {
+			return nil, fmt.Errorf("no pinned version of %s is available offline, use --bappa-version", module)
+		}
+		goMod.AddNewRequire(module, deps.BappaVersion, false)
+	}
+
+	goMod.SetRequireSeparateIndirect(goMod.Require)
+	goMod.Cleanup()
+	return goMod.Format()
+}
+
+// offlineGoSum returns bappacreate's go.sum without the generator's own modules
+// and without bappa checksums that don't match an overridden bappa version
+func offlineGoSum(deps dependencyOptions) ([]byte, error) {
+	data, err := bappacreateModule.ReadFile("go.sum")
+	if err != nil {
+		return nil, err
+	}
+
+	var sum bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		if generatorOnlyModules[fields[0]] {
+			continue
+		}
+		if isBappaModule(fields[0]) && deps.BappaVersion != "" && strings.TrimSuffix(fields[1], "/go.mod") != deps.BappaVersion {
+			continue
+		}
+		sum.WriteString(scanner.Text())
+		sum.WriteByte('\n')
+	}
+	return sum.Bytes(), scanner.Err()
+}
+
+// workspaceFile builds a go.work that uses every module directory in dirs. Its go
+// version is the highest one required by the modules' go.mod files in fsys.
+func workspaceFile(fsys fs.FS, dirs []string) ([]byte, error) {
+	work := &modfile.WorkFile{Syntax: &modfile.FileSyntax{}}
+
+	goVersion := ""
+	for _, dir := range dirs {
+		goModPath := path.Join(dir, "go.mod")
+		data, err := fs.ReadFile(fsys, goModPath)
+		if err != nil {
+			return nil, err
+		}
+		goMod, err := modfile.ParseLax(goModPath, data, nil)
+		if err != nil {
+			return nil, err
+		}
+		if goMod.Go != nil && goversion.Compare("go"+goMod.Go.Version, "go"+goVersion) > 0 {
+			goVersion = goMod.Go.Version
+		}
+
+		if dir != "." {
+			dir = "./" + dir
+		}
+		work.AddNewUse(dir, "")
+	}
+
+	if goVersion != "" {
+		if err := work.AddGoStmt(goVersion); err != nil {
+			return nil, err
+		}
+	}
+	work.Cleanup()
+	return modfile.Format(work.Syntax), nil
+}
+
+// workspacePackages returns patterns matching the packages of every module
+// directory in dirs, e.g. ./client/... ./server/..., to build a workspace from
+// its root. ./... stops at nested modules even in a workspace, and the work
+// pattern needs Go 1.25.
+func workspacePackages(dirs []string) []string {
+	patterns := make([]string, len(dirs))
+	for i, dir := range dirs {
+		if dir == "." {
+			patterns[i] = "./..."
+		} else {
+			patterns[i] = "./" + dir + "/..."
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/modfile"
+)
+
+func TestDependencyOptionsValidate(t *testing.T) {
+	tests := []struct {
+		deps dependencyOptions
+		err  string
+	}{
+		{deps: dependencyOptions{}},
+		{deps: dependencyOptions{Offline: true}},
+		{deps: dependencyOptions{BappaVersion: "main"}},
+		{deps: dependencyOptions{BappaVersion: "v0.1.0", Offline: true}},
+		{deps: dependencyOptions{BappaVersion: "v0.0.0-20250420132432-5606172c9a41", Offline: true}},
+		{deps: dependencyOptions{BappaVersion: "main", Offline: true}, err: "must be a semantic version or pseudo-version when used with --offline"},
+		{deps: dependencyOptions{BappaVersion: "v0.1.0@latest"}, err: "invalid --bappa-version"},
+		{deps: dependencyOptions{BappaVersion: "v0.1.0 v0.2.0"}, err: "invalid --bappa-version"},
+	}
+	for _, tt := range tests {
+		err := tt.deps.validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("validate(%+v): %v", tt.deps, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("validate(%+v) = %v, want an error containing %q", tt.deps, err, tt.err)
+		}
+	}
+}
+
+func TestOfflineGoMod(t *testing.T) {
+	data, err := bappacreateModule.ReadFile("go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned := map[string]string{}
+	for _, req := range own.Require {
+		pinned[req.Mod.Path] = req.Mod.Version
+	}
+	coldbrew := bappaModuleRoot + "/coldbrew"
+	drip := bappaModuleRoot + "/drip"
+
+	tests := []struct {
+		name    string
+		modules []string
+		deps    dependencyOptions
+		want    map[string]string // required versions, "" for modules that must be missing
+		err     string
+	}{
+		{
+			name:    "bappacreate's versions",
+			modules: []string{coldbrew},
+			want:    map[string]string{coldbrew: pinned[coldbrew], drip: pinned[drip], "golang.org/x/mod": "", "golang.org/x/tools": ""},
+		},
+		{
+			name:    "--bappa-version",
+			modules: []string{coldbrew, bappaModuleRoot + "/newmodule"},
+			deps:    dependencyOptions{BappaVersion: "v0.2.0", Offline: true},
+			want:    map[string]string{coldbrew: "v0.2.0", drip: "v0.2.0", bappaModuleRoot + "/newmodule": "v0.2.0", "github.com/hajimehoshi/ebiten/v2": pinned["github.com/hajimehoshi/ebiten/v2"]},
+		},
+		{
+			name:    "module bappacreate doesn't require",
+			modules: []string{bappaModuleRoot + "/newmodule"},
+			err:     "no pinned version of " + bappaModuleRoot + "/newmodule is available offline, use --bappa-version",
+		},
+	}
+	for _, tt := range tests {
+		data, err := offlineGoMod("example.com/game", tt.modules, tt.deps)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: offlineGoMod = %v, want error %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		goMod, err := modfile.Parse("go.mod", data, nil)
+		if err != nil {
+			t.Fatalf("%s: the go.mod doesn't parse: %v\n%s", tt.name, err, data)
+		}
+		if goMod.Module.Mod.Path != "example.com/game" || goMod.Go == nil || goMod.Go.Version != own.Go.Version {
+			t.Errorf("%s: go.mod doesn't declare the module with bappacreate's go version:\n%s", tt.name, data)
+		}
+		required := map[string]string{}
+		for _, req := range goMod.Require {
+			required[req.Mod.Path] = req.Mod.Version
+		}
+		for module, version := range tt.want {
+			if required[module] != version {
+				t.Errorf("%s: go.mod requires %s %q, want %q:\n%s", tt.name, module, required[module], version, data)
+			}
+		}
+	}
+}
+
+func TestOfflineGoSum(t *testing.T) {
+	sum, err := offlineGoSum(dependencyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(sum), bappaModuleRoot+"/coldbrew ") || strings.Contains(string(sum), "golang.org/x/tools ") {
+		t.Errorf("go.sum doesn't hold bappacreate's checksums without its own tools:\n%s", sum)
+	}
+
+	// Checksums of other bappa versions would only fail verification
+	sum, err = offlineGoSum(dependencyOptions{BappaVersion: "v0.2.0", Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sum), bappaModuleRoot+"/") || !strings.Contains(string(sum), "github.com/hajimehoshi/ebiten/v2 ") {
+		t.Errorf("go.sum with --bappa-version keeps other bappa checksums or drops the rest:\n%s", sum)
+	}
+}
+
+func TestWorkspacePackages(t *testing.T) {
+	got := workspacePackages([]string{".", "client", "shared/tools"})
+	want := []string{"./...", "./client/...", "./shared/tools/..."}
+	if !slices.Equal(got, want) {
+		t.Errorf("workspacePackages = %q, want %q", got, want)
+	}
+}
+
+// TestWorkspaceBuildFromRoot builds a workspace like the one of a netcode project,
+// modules requiring each other by their module paths, from its root with the
+// command the project's instructions print
+func TestWorkspaceBuildFromRoot(t *testing.T) {
+	if testing.Short() {
+		t.Skip("not running the go command in -short mode")
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"shared/go.mod":       "module example.com/game/shared\n\ngo 1.24.2\n",
+		"shared/scenes/a.go":  "package scenes\n\nconst Name = \"scene one\"\n",
+		"client/go.mod":       "module example.com/game/client\n\ngo 1.24.2\n\nrequire example.com/game/shared v0.0.0\n",
+		"client/main.go":      "package main\n\nimport \"example.com/game/shared/scenes\"\n\nfunc main() { println(scenes.Name) }\n",
+		"standalone/go.mod":   "module example.com/game/standalone\n\ngo 1.24\n\nrequire example.com/game/shared v0.0.0\n",
+		"standalone/main.go":  "package main\n\nimport \"example.com/game/shared/scenes\"\n\nfunc main() { println(scenes.Name) }\n",
+		"standalone/x/mod.go": "package x\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	modules := []string{"client", "shared", "standalone"}
+	goWork, err := workspaceFile(os.DirFS(dir), modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(goWork), "go 1.24.2\n") {
+		t.Errorf("go.work doesn't use the highest go version of its modules:\n%s", goWork)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.work"), goWork, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The go command of the tests must not reach the network or another toolchain
+	goInRoot := func(command string) string {
+		t.Helper()
+		cmd := exec.Command("go", append([]string{command}, workspacePackages(modules)...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOPROXY=off", "GOFLAGS=", "GOTOOLCHAIN=local")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("go %s in the workspace root: %v\n%s", command, err, out)
+		}
+		return string(out)
+	}
+	want := []string{"example.com/game/client", "example.com/game/shared/scenes", "example.com/game/standalone", "example.com/game/standalone/x"}
+	if got := strings.Fields(goInRoot("list")); !slices.Equal(got, want) {
+		t.Errorf("the packages built from the root are %q, want %q", got, want)
+	}
+	goInRoot("build")
+}
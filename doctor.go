@@ -0,0 +1,609 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+func printDoctorUsage() {
+	fmt.Println("Usage: bappacreate doctor [dir]")
+	fmt.Println("       bappacreate doctor --template <name> | --all")
+	fmt.Println()
+	fmt.Println("Checks a generated project, or the project a template generates, for problems")
+	fmt.Println("that only show up when the game is built or run:")
+	fmt.Println("  - asset paths in Go files that aren't in an assets/ directory")
+	fmt.Println("  - //go:embed patterns matching no files")
+	fmt.Println("  - imports of template paths that aren't rewritten, or of project packages")
+	fmt.Println("    that don't exist")
+	fmt.Println("  - bappa modules imported but not required by go.mod or the manifest")
+	fmt.Println("  - LDtk tilesets and level backgrounds that don't exist")
+	fmt.Println("  - manifest fields that don't match the template")
+	fmt.Println()
+	fmt.Println("dir defaults to the current directory. A directory with a template.json is")
+	fmt.Println("checked as a template, any other as the project it belongs to.")
+}
+
+func runDoctor(args []string) error {
+	flags := newFlagSet("doctor", printDoctorUsage)
+	name := flags.String("template", "", "check the template called name instead of a directory")
+	all := flags.Bool("all", false, "check every available template")
+	opts := templateOptions{}
+	addTemplateFlags(flags, &opts)
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(positional) > 1:
+		return usageErrorf(flags, "expected at most one directory, got %d", len(positional))
+	case len(positional) == 1 && (*name != "" || *all):
+		return usageErrorf(flags, "a directory cannot be combined with --template or --all")
+	case *name != "" && *all:
+		return usageErrorf(flags, "--template and --all cannot be used together")
+	}
+
+	dir := "."
+	if len(positional) == 1 {
+		dir = positional[0]
+	}
+	var problems int
+	switch {
+	case *name != "" || *all:
+		problems, err = doctorTemplates(opts, *name)
+	case fileExists(filepath.Join(dir, manifestFileName)):
+		problems, err = doctorTemplates(templateOptions{Dir: dir}, "")
+	default:
+		problems, err = doctorProject(dir)
+	}
+	if err != nil {
+		return err
+	}
+	if problems > 0 {
+		return fmt.Errorf("found %d problem(s)", problems)
+	}
+	return nil
+}
+
+// doctorTemplates checks the template called name, or every template when name is
+// empty, and returns the number of problems found
+func doctorTemplates(opts templateOptions, name string) (int, error) {
+	templates, cleanup, err := loadTemplates(opts)
+	var invalid *manifestError
+	if errors.As(err, &invalid) {
+		// Invalid manifests are what doctor looks for, but they stop loading
+		fmt.Printf("Checking templates\n  %v\n", err)
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+
+	problems := 0
+	for _, template := range templates {
+		if name != "" && template.Manifest.Name != name {
+			continue
+		}
+		resolved, err := resolveTemplate(template, templates)
+		if err != nil {
+			return 0, err
+		}
+		d := checkTemplate(resolved)
+		if dir, ok := template.Source.(dirTemplate); ok && filepath.Base(dir.dir) != template.Manifest.Name {
+			d.report(manifestFileName, 0, "name %q does not match the directory %s", template.Manifest.Name, filepath.Base(dir.dir))
+		}
+		d.print(fmt.Sprintf("template %s (%s)", template.Manifest.Name, sourceName(template.Source)))
+		problems += len(d.problems)
+		if name != "" {
+			return problems, nil
+		}
+	}
+	if name != "" {
+		_, err := findTemplate(name, templates)
+		return 0, err
+	}
+	return problems, nil
+}
+
+// doctorProject checks the project dir belongs to and returns the number of
+// problems found
+func doctorProject(dir string) (int, error) {
+	proj, err := findProject(dir)
+	if err != nil {
+		return 0, err
+	}
+	tree := &doctorTree{files: map[string]bool{}, read: func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(proj.Root, filepath.FromSlash(name)))
+	}}
+	err = filepath.WalkDir(proj.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != proj.Root && strings.HasPrefix(d.Name(), ".") {
+			return fs.SkipDir
+		}
+		if !d.IsDir() {
+			rel, err := filepath.Rel(proj.Root, p)
+			if err != nil {
+				return err
+			}
+			tree.files[filepath.ToSlash(rel)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	d := &doctor{tree: tree}
+	d.check()
+	d.print("project " + proj.Root)
+	return len(d.problems), nil
+}
+
+// checkTemplate checks the project template generates, with its problems reported
+// at the template files they come from
+func checkTemplate(template availableTemplate) *doctor {
+	d := &doctor{}
+	names, err := resolveProjectNames("doctor/"+template.Manifest.Name, "")
+	if err != nil {
+		d.report(manifestFileName, 0, "%v", err)
+		return d
+	}
+	plan, err := buildPlan(template.Manifest, template.Source, names, dependencyOptions{Offline: true})
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			d.report(manifestFileName, 0, "%s", line)
+		}
+		return d
+	}
+
+	files := map[string]plannedFile{}
+	d.tree = &doctorTree{files: map[string]bool{}, origins: map[string]string{}, generated: map[string]bool{}}
+	for _, file := range plan.Files {
+		files[file.Path] = file
+		d.tree.files[file.Path] = true
+		if file.Generated != "" {
+			d.tree.origins[file.Path] = file.Path + " (generated)"
+			d.tree.generated[file.Path] = true
+		} else {
+			d.tree.origins[file.Path] = file.Source
+		}
+	}
+	d.tree.read = func(name string) ([]byte, error) {
+		return renderFile(plan, files[name])
+	}
+	d.resolution = &plan.Resolution
+	d.modulePaths = plan.Modules
+	d.check()
+	return d
+}
+
+// doctorTree is the files of a generated project, or of the project a template
+// generates. Paths are slash separated and relative to the project root.
+type doctorTree struct {
+	files     map[string]bool
+	read      func(name string) ([]byte, error)
+	origins   map[string]string // file problems are reported at, when not the path itself
+	generated map[string]bool   // files bappacreate writes without a template source
+}
+
+// origin returns where problems in the file name are reported
+func (t *doctorTree) origin(name string) string {
+	if origin, ok := t.origins[name]; ok {
+		return origin
+	}
+	return name
+}
+
+// sortedFiles returns the paths of the tree in order
+func (t *doctorTree) sortedFiles() []string {
+	files := make([]string, 0, len(t.files))
+	for name := range t.files {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// problem is something doctor found, at a line of a file or, when Line is 0, the file
+type problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.File, p.Message)
+}
+
+// doctor runs the checks on a tree
+type doctor struct {
+	tree        *doctorTree
+	problems    []problem
+	resolution  *Resolution         // window size of the template's manifest, when checking a template
+	modulePaths []string            // bappa modules of the template's manifest, when checking a template
+	modules     map[string]string   // module path of every go.mod, by directory
+	requires    map[string][]string // bappa modules each go.mod requires, by directory
+	missing     map[string]bool     // go.mod and bappa module it doesn't require, already reported
+	assetDirs   []string
+}
+
+func (d *doctor) report(file string, line int, format string, args ...any) {
+	if d.tree != nil {
+		file = d.tree.origin(file)
+	}
+	d.problems = append(d.problems, problem{File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *doctor) print(what string) {
+	fmt.Printf("Checking %s\n", what)
+	if len(d.problems) == 0 {
+		fmt.Println("  No problems found")
+		return
+	}
+	sort.SliceStable(d.problems, func(i, j int) bool {
+		a, b := d.problems[i], d.problems[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	for _, p := range d.problems {
+		fmt.Printf("  %s\n", p)
+	}
+}
+
+// check runs every check on the tree
+func (d *doctor) check() {
+	d.modules = map[string]string{}
+	d.requires = map[string][]string{}
+	d.missing = map[string]bool{}
+	for _, name := range d.tree.sortedFiles() {
+		if path.Base(name) == "go.mod" {
+			d.readGoMod(name)
+		}
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if path.Base(dir) == "assets" && !slices.Contains(d.assetDirs, dir) {
+				d.assetDirs = append(d.assetDirs, dir)
+			}
+		}
+	}
+	sort.Strings(d.assetDirs)
+
+	for _, name := range d.tree.sortedFiles() {
+		switch path.Ext(name) {
+		case ".go":
+			d.checkGoFile(name)
+		case ".ldtk":
+			d.checkLDtk(name)
+		}
+	}
+}
+
+func (d *doctor) readGoMod(name string) {
+	data, err := d.tree.read(name)
+	if err != nil {
+		d.report(name, 0, "%v", err)
+		return
+	}
+	file, err := modfile.Parse(name, data, nil)
+	if err != nil {
+		d.report(name, 0, "%v", err)
+		return
+	}
+	if file.Module == nil {
+		d.report(name, 0, "no module directive")
+		return
+	}
+	dir := path.Dir(name)
+	d.modules[dir] = file.Module.Mod.Path
+	for _, req := range file.Require {
+		d.requires[dir] = append(d.requires[dir], req.Mod.Path)
+	}
+}
+
+// moduleOf returns the directory of the go.mod the file name belongs to
+func (d *doctor) moduleOf(name string) (string, bool) {
+	for dir := path.Dir(name); ; dir = path.Dir(dir) {
+		if _, ok := d.modules[dir]; ok {
+			return dir, true
+		}
+		if dir == "." {
+			return "", false
+		}
+	}
+}
+
+// packageDir returns the directory of the project package importPath, if it is one
+func (d *doctor) packageDir(importPath string) (string, bool) {
+	best, bestDir := "", ""
+	for dir, modulePath := range d.modules {
+		if rest, ok := replacePathPrefix(importPath, modulePath, ""); ok && len(modulePath) > len(best) {
+			best, bestDir = modulePath, path.Join(dir, strings.TrimPrefix(rest, "/"))
+		}
+	}
+	return bestDir, best != ""
+}
+
+// hasGoFiles reports whether dir directly contains Go files
+func (d *doctor) hasGoFiles(dir string) bool {
+	for name := range d.tree.files {
+		if path.Dir(name) == dir && path.Ext(name) == ".go" {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *doctor) checkGoFile(name string) {
+	src, err := d.tree.read(name)
+	if err != nil {
+		d.report(name, 0, "%v", err)
+		return
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		d.report(name, 0, "%v", err)
+		return
+	}
+	line := func(pos token.Pos) int { return fset.Position(pos).Line }
+
+	d.checkImports(name, file, line)
+	d.checkEmbeds(name, file, line)
+	d.checkAssetPaths(name, file, line)
+	if d.resolution != nil {
+		d.checkResolution(name, file, line)
+	}
+}
+
+// checkImports reports template imports that weren't rewritten, imports of project
+// packages that don't exist and bappa modules go.mod doesn't require
+func (d *doctor) checkImports(name string, file *ast.File, line func(token.Pos) int) {
+	moduleDir, inModule := d.moduleOf(name)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		if strings.HasPrefix(importPath, "github.com/TheBitDrifter/bappacreate/templates/") {
+			d.report(name, line(spec.Pos()), "import %s still points at the templates, the package is missing from the template or its common packages", importPath)
+			continue
+		}
+		if dir, ok := d.packageDir(importPath); ok {
+			if !d.hasGoFiles(dir) {
+				d.report(name, line(spec.Pos()), "imports %s, but %s/ has no Go files", importPath, dir)
+			}
+			continue
+		}
+
+		module, ok := replacePathPrefix(importPath, bappaModuleRoot, "")
+		if !ok || !inModule {
+			continue
+		}
+		module = bappaModuleRoot + "/" + strings.Split(strings.TrimPrefix(module, "/"), "/")[0]
+		goMod := path.Join(moduleDir, "go.mod")
+		required := d.requires[moduleDir]
+		if d.tree.generated[goMod] {
+			// Offline, the generated go.mod requires every module bappacreate knows
+			required = d.modulePaths
+		}
+		if slices.Contains(required, module) || d.missing[goMod+" "+module] {
+			continue
+		}
+		// Reported at the first import only, every file of the module imports it
+		d.missing[goMod+" "+module] = true
+		if d.tree.generated[goMod] {
+			d.report(name, line(spec.Pos()), "imports %s, but %s is not in the modules of %s", importPath, strings.TrimPrefix(module, bappaModuleRoot+"/"), manifestFileName)
+		} else {
+			d.report(name, line(spec.Pos()), "imports %s, but %s doesn't require %s", importPath, goMod, module)
+		}
+	}
+}
+
+// checkEmbeds reports //go:embed patterns that match no files, which fails the build
+func (d *doctor) checkEmbeds(name string, file *ast.File, line func(token.Pos) int) {
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			args, ok := strings.CutPrefix(comment.Text, "//go:embed ")
+			if !ok {
+				continue
+			}
+			patterns, err := embedPatterns(args)
+			if err != nil {
+				d.report(name, line(comment.Pos()), "invalid //go:embed: %v", err)
+				continue
+			}
+			for _, pattern := range patterns {
+				if !d.embedMatches(path.Dir(name), pattern) {
+					d.report(name, line(comment.Pos()), "//go:embed pattern %s matches no files", pattern)
+				}
+			}
+		}
+	}
+}
+
+// embedPatterns splits the arguments of a //go:embed directive, which may be quoted
+func embedPatterns(args string) ([]string, error) {
+	var patterns []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		if args[0] != '"' && args[0] != '`' {
+			pattern, rest, _ := strings.Cut(args, " ")
+			patterns = append(patterns, pattern)
+			args = rest
+			continue
+		}
+		quoted, err := strconv.QuotedPrefix(args)
+		if err != nil {
+			return nil, err
+		}
+		pattern, _ := strconv.Unquote(quoted)
+		patterns = append(patterns, pattern)
+		args = args[len(quoted):]
+	}
+	return patterns, nil
+}
+
+// embedMatches reports whether the //go:embed pattern in dir matches a file. A
+// matched directory embeds its files, except those starting with . or _.
+func (d *doctor) embedMatches(dir, pattern string) bool {
+	pattern, all := strings.CutPrefix(pattern, "all:")
+	for name := range d.tree.files {
+		rel, ok := replacePathPrefix(name, dir, "")
+		if dir == "." {
+			rel, ok = "/"+name, true
+		}
+		if !ok || rel == "" {
+			continue
+		}
+		elements := strings.Split(strings.TrimPrefix(rel, "/"), "/")
+		for i := range elements {
+			if matched, _ := path.Match(pattern, strings.Join(elements[:i+1], "/")); !matched {
+				continue
+			}
+			if all || !slices.ContainsFunc(elements[i+1:], func(e string) bool { return strings.HasPrefix(e, ".") || strings.HasPrefix(e, "_") }) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkAssetPaths reports string literals naming asset files that no assets/
+// directory has. Paths built by concatenation can't be checked.
+func (d *doctor) checkAssetPaths(name string, file *ast.File, line func(token.Pos) int) {
+	if len(d.assetDirs) == 0 {
+		return
+	}
+	concatenated := map[*ast.BasicLit]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.BinaryExpr:
+			for _, operand := range []ast.Expr{node.X, node.Y} {
+				if lit, ok := operand.(*ast.BasicLit); ok {
+					concatenated[lit] = true
+				}
+			}
+		case *ast.BasicLit:
+			if node.Kind != token.STRING || concatenated[node] {
+				break
+			}
+			value, err := strconv.Unquote(node.Value)
+			if err != nil || !binaryExtensions[strings.ToLower(path.Ext(value))] || strings.ContainsAny(value, "%*") || path.IsAbs(value) {
+				break
+			}
+			if !d.assetExists(value) {
+				d.report(name, line(node.Pos()), "asset %s is not in %s", value, strings.Join(d.assetDirs, ", "))
+			}
+		}
+		return true
+	})
+}
+
+// assetExists reports whether an assets directory has the file asset. Assets are
+// looked up with and without their images/ or sounds/ directory, and paths starting
+// with assets/ from the directory holding it.
+func (d *doctor) assetExists(asset string) bool {
+	asset = path.Clean(asset)
+	for _, dir := range d.assetDirs {
+		for _, candidate := range []string{asset, "images/" + asset, "sounds/" + asset, "../" + asset} {
+			if d.tree.files[path.Join(dir, candidate)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkResolution reports RESOLUTION_X and RESOLUTION_Y constants that differ from
+// the template's manifest
+func (d *doctor) checkResolution(name string, file *ast.File, line func(token.Pos) int) {
+	want := map[string]int{"RESOLUTION_X": d.resolution.Width, "RESOLUTION_Y": d.resolution.Height}
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		for i, ident := range spec.Names {
+			expected, ok := want[ident.Name]
+			if !ok || i >= len(spec.Values) {
+				continue
+			}
+			lit, ok := spec.Values[i].(*ast.BasicLit)
+			if !ok || lit.Kind != token.INT {
+				continue
+			}
+			if value, err := strconv.Atoi(lit.Value); err == nil && value != expected {
+				d.report(name, line(lit.Pos()), "%s is %d, but the resolution in %s is %s; use {{.Resolution.Width}} and {{.Resolution.Height}} in a .tmpl file", ident.Name, value, manifestFileName, d.resolution)
+			}
+		}
+		return true
+	})
+}
+
+// ldtkProject is the part of an LDtk project file that references other files
+type ldtkProject struct {
+	Defs struct {
+		Tilesets []struct {
+			Identifier string  `json:"identifier"`
+			RelPath    *string `json:"relPath"`
+		} `json:"tilesets"`
+	} `json:"defs"`
+	Levels []struct {
+		Identifier string  `json:"identifier"`
+		BgRelPath  *string `json:"bgRelPath"`
+	} `json:"levels"`
+}
+
+// checkLDtk reports tileset images and level backgrounds the LDtk project references
+// but the tree doesn't have
+func (d *doctor) checkLDtk(name string) {
+	data, err := d.tree.read(name)
+	if err != nil {
+		d.report(name, 0, "%v", err)
+		return
+	}
+	var project ldtkProject
+	if err := json.Unmarshal(data, &project); err != nil {
+		d.report(name, 0, "invalid LDtk project: %v", err)
+		return
+	}
+
+	missing := func(relPath *string) (string, bool) {
+		if relPath == nil || *relPath == "" {
+			return "", false
+		}
+		target := path.Join(path.Dir(name), *relPath)
+		return *relPath, !d.tree.files[target]
+	}
+	for _, tileset := range project.Defs.Tilesets {
+		if relPath, ok := missing(tileset.RelPath); ok {
+			d.report(name, 0, "tileset %s uses %s, which doesn't exist", tileset.Identifier, relPath)
+		}
+	}
+	for _, level := range project.Levels {
+		if relPath, ok := missing(level.BgRelPath); ok {
+			d.report(name, 0, "level %s has the background %s, which doesn't exist", level.Identifier, relPath)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testDoctor returns a doctor checking a project made of files, by path
+func testDoctor(files map[string]string) *doctor {
+	tree := &doctorTree{files: map[string]bool{}, read: func(name string) ([]byte, error) {
+		return []byte(files[name]), nil
+	}}
+	for name := range files {
+		tree.files[name] = true
+	}
+	return &doctor{tree: tree}
+}
+
+// problemLines returns the problems d found as "file:line: message" lines
+func problemLines(d *doctor) []string {
+	lines := make([]string, len(d.problems))
+	for i, p := range d.problems {
+		lines[i] = p.String()
+	}
+	return lines
+}
+
+func TestEmbedPatterns(t *testing.T) {
+	tests := []struct {
+		args string
+		want []string
+		err  bool
+	}{
+		{args: "assets", want: []string{"assets"}},
+		{args: "  a.png   b/*.png ", want: []string{"a.png", "b/*.png"}},
+		{args: `"with space.png" all:assets`, want: []string{"with space.png", "all:assets"}},
+		{args: "`raw name` x", want: []string{"raw name", "x"}},
+		{args: `"unterminated`, err: true},
+	}
+	for _, tt := range tests {
+		got, err := embedPatterns(tt.args)
+		if (err != nil) != tt.err || !slices.Equal(got, tt.want) {
+			t.Errorf("embedPatterns(%q) = %q, %v; want %q, error %v", tt.args, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestEmbedMatches(t *testing.T) {
+	d := testDoctor(map[string]string{
+		"assets/images/player.png":   "",
+		"assets/sounds/.hidden.wav":  "",
+		"only/.hidden/file.txt":      "",
+		"only/_private.txt":          "",
+		"client/assets/levels.ldtk":  "",
+		"client/assets/tiles/a.png":  "",
+		"client/main.go":             "",
+		"shared/_layers/nested/x.go": "",
+	})
+	tests := []struct {
+		dir, pattern string
+		want         bool
+	}{
+		{".", "assets", true},
+		{".", "assets/images/*.png", true},
+		{".", "assets/*.png", false},
+		{".", "assets/images/player.png", true},
+		{".", "missing", false},
+		{".", "only", false}, // only hidden and _ files
+		{".", "all:only", true},
+		{".", "only/_private.txt", true}, // named explicitly
+		{"client", "assets", true},
+		{"client", "assets/tiles", true},
+		{"client", "main.go", true},
+		{"client", "levels.ldtk", false},
+		{"shared", "_layers", true}, // named explicitly, only names below it are excluded
+		{"shared", "*", true},       // a match names the directory too
+	}
+	for _, tt := range tests {
+		if got := d.embedMatches(tt.dir, tt.pattern); got != tt.want {
+			t.Errorf("embedMatches(%q, %q) = %v, want %v", tt.dir, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestAssetExists(t *testing.T) {
+	d := testDoctor(map[string]string{
+		"assets/images/characters/box_man_sheet.png": "",
+		"assets/sounds/jump.wav":                     "",
+		"sharedclient/assets/images/tiles.png":       "",
+	})
+	d.check()
+	tests := []struct {
+		asset string
+		want  bool
+	}{
+		{"images/characters/box_man_sheet.png", true},
+		{"characters/box_man_sheet.png", true},
+		{"sounds/jump.wav", true},
+		{"jump.wav", true},
+		{"./sounds/jump.wav", true},
+		{"assets/sounds/jump.wav", true},
+		{"tiles.png", true},
+		{"sounds/music.wav", false},
+		{"images/box_man_sheet.png", false},
+	}
+	for _, tt := range tests {
+		if got := d.assetExists(tt.asset); got != tt.want {
+			t.Errorf("assetExists(%q) = %v, want %v", tt.asset, got, tt.want)
+		}
+	}
+}
+
+func TestCheckLDtk(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		want    []string
+	}{
+		{
+			name:    "references exist",
+			project: `{"defs": {"tilesets": [{"identifier": "Tiles", "relPath": "tiles.png"}, {"identifier": "Internal_Icons", "relPath": null}]}, "levels": [{"identifier": "Level_0", "bgRelPath": "../images/bg.png"}, {"identifier": "Level_1", "bgRelPath": null}]}`,
+		},
+		{
+			name:    "missing tileset and background",
+			project: `{"defs": {"tilesets": [{"identifier": "Tiles", "relPath": "missing.png"}]}, "levels": [{"identifier": "Level_0", "bgRelPath": "bg.png"}]}`,
+			want: []string{
+				"assets/levels/data.ldtk: tileset Tiles uses missing.png, which doesn't exist",
+				"assets/levels/data.ldtk: level Level_0 has the background bg.png, which doesn't exist",
+			},
+		},
+		{
+			name:    "not json",
+			project: `{"defs": `,
+			want:    []string{"assets/levels/data.ldtk: invalid LDtk project: unexpected end of JSON input"},
+		},
+	}
+	for _, tt := range tests {
+		d := testDoctor(map[string]string{
+			"assets/levels/data.ldtk": tt.project,
+			"assets/levels/tiles.png": "",
+			"assets/images/bg.png":    "",
+		})
+		d.checkLDtk("assets/levels/data.ldtk")
+		if got := problemLines(d); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got problems\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestCheckImports(t *testing.T) {
+	goMod := "module example.com/game\n\ngo 1.24\n\nrequire github.com/TheBitDrifter/bappa/blueprint v0.0.1\n"
+	tests := []struct {
+		name    string
+		imports string
+		want    []string
+	}{
+		{
+			name:    "valid imports",
+			imports: `"fmt"; "example.com/game/scenes"; "github.com/TheBitDrifter/bappa/blueprint/vector"`,
+		},
+		{
+			name:    "template path left behind",
+			imports: `"github.com/TheBitDrifter/bappacreate/templates/common/coresystems"`,
+			want:    []string{"main.go:3: import github.com/TheBitDrifter/bappacreate/templates/common/coresystems still points at the templates, the package is missing from the template or its common packages"},
+		},
+		{
+			name:    "missing project package",
+			imports: `"example.com/game/coresystems"`,
+			want:    []string{"main.go:3: imports example.com/game/coresystems, but coresystems/ has no Go files"},
+		},
+		{
+			name:    "bappa module not required, reported once",
+			imports: `"github.com/TheBitDrifter/bappa/warehouse"; "github.com/TheBitDrifter/bappa/warehouse/query"`,
+			want:    []string{"main.go:3: imports github.com/TheBitDrifter/bappa/warehouse, but go.mod doesn't require github.com/TheBitDrifter/bappa/warehouse"},
+		},
+	}
+	for _, tt := range tests {
+		d := testDoctor(map[string]string{
+			"go.mod":          goMod,
+			"main.go":         "package main\n\nimport (" + tt.imports + ")\n",
+			"scenes/scene.go": "package scenes\n",
+		})
+		d.check()
+		if got := problemLines(d); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got problems\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestCheckImportsTemplateModules(t *testing.T) {
+	// The go.mod of a template is generated from the modules of its manifest
+	d := testDoctor(map[string]string{
+		"go.mod":  "module example.com/game\n\nrequire github.com/TheBitDrifter/bappa/table v0.0.1\n",
+		"main.go": "package main\n\nimport \"github.com/TheBitDrifter/bappa/table\"\n",
+	})
+	d.tree.origins = map[string]string{"go.mod": "go.mod (generated)"}
+	d.tree.generated = map[string]bool{"go.mod": true}
+	d.modulePaths = []string{"github.com/TheBitDrifter/bappa/coldbrew"}
+	d.check()
+	want := []string{"main.go:3: imports github.com/TheBitDrifter/bappa/table, but table is not in the modules of template.json"}
+	if got := problemLines(d); !slices.Equal(got, want) {
+		t.Errorf("got problems %q, want %q", got, want)
+	}
+}
+
+// TestDoctorBuiltinTemplates checks every built-in template, and every genre with
+// each combination of its features, the way doctor --all does
+func TestDoctorBuiltinTemplates(t *testing.T) {
+	templates := builtinTemplates(t)
+	for _, tc := range goldenCases(t) {
+		template, err := selectTemplate(tc.opts.Template, tc.opts.Genre, tc.opts.With, templates)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if d := checkTemplate(template); len(d.problems) > 0 {
+			t.Errorf("%s:\n  %s", tc.name, strings.Join(problemLines(d), "\n  "))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+//go:build ignore
+
+// gen_archives packs every template that contains nested Go modules into
+// templates/<name>.zip. //go:embed skips directories holding a go.mod, so
+// those templates can only be embedded as an archive.
+//
+// Run it with `go generate` after changing such a template.
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const templatesDir = "templates"
+
+// Fixed modification time and mode so regenerating an unchanged template gives the same archive
+var archiveTime = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func main() {
+	entries, err := os.ReadDir(templatesDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(templatesDir, entry.Name())
+		nested, err := hasNestedModule(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !nested {
+			continue
+		}
+
+		archivePath := dir + ".zip"
+		if err := writeArchive(dir, archivePath); err != nil {
+			log.Fatalf("%s: %v", archivePath, err)
+		}
+		fmt.Println("Wrote", archivePath)
+	}
+}
+
+// hasNestedModule reports whether any directory below dir has a go.mod
+func hasNestedModule(dir string) (bool, error) {
+	found := false
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && d.Name() == "go.mod" && filepath.Dir(p) != dir {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found, err
+}
+
+// writeArchive zips the files below dir into archivePath, with paths relative to dir
+func writeArchive(dir, archivePath string) error {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		header := &zip.FileHeader{
+			Name:     filepath.ToSlash(rel),
+			Method:   zip.Deflate,
+			Modified: archiveTime,
+		}
+		header.SetMode(0644)
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		w, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(archivePath, buf.Bytes(), 0644)
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/modfile"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// Directory holding one golden file tree per generated template
+const goldenDir = "testdata/golden"
+
+// Environment variable naming a checkout of the bappa repository, with one
+// directory per module, to compile the generated projects against
+const bappaDirEnv = "BAPPACREATE_BAPPA_DIR"
+
+// Environment variable that, set to 1, compiles the generated projects, which
+// fails when the bappa modules aren't available. Setting bappaDirEnv does too.
+const compileEnv = "BAPPACREATE_COMPILE"
+
+// goldenCase is a project generated by TestGenerateTemplates
+type goldenCase struct {
+	name string // name of the template, also of the project and its golden file
+	opts projectOptions
+}
+
+// TestGenerateTemplates generates every built-in template, and every genre with
+// every combination of its features, offline. It compares the files of each
+// project with its golden file and compiles the project with go vet and go build.
+//
+// Run it with -update to rewrite the golden files after changing a template.
+func TestGenerateTemplates(t *testing.T) {
+	// Templates installed by whoever runs the tests must not replace built-in ones
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer func(v string) { version = v }(version)
+	version = "golden"
+
+	// Subtests run in the directory of their project
+	golden, err := filepath.Abs(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := goldenCases(t)
+	var bappa map[string]string
+	if compileRequested() {
+		bappa = bappaModuleDirs(t)
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+
+			opts := tc.opts
+			opts.Name = "golden/" + tc.name
+			opts.Deps.Offline = true
+			opts.NoHooks = true // git init and friends would only add noise to the tree
+			if err := createProject(opts); err != nil {
+				t.Fatalf("generating %s: %v", tc.name, err)
+			}
+
+			projectDir := filepath.Join(dir, tc.name)
+			checkGolden(t, filepath.Join(golden, tc.name+".txt"), fileTree(t, projectDir))
+			compileProject(t, projectDir, bappa)
+		})
+	}
+
+	checkStaleGolden(t, cases)
+}
+
+// goldenCases lists the built-in templates, followed by the genre and feature
+// combinations no preset template covers. Default features are in every case.
+func goldenCases(t *testing.T) []goldenCase {
+	templates, cleanup, err := loadTemplates(templateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	layers, err := loadLayers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cases []goldenCase
+	for _, template := range templates {
+		cases = append(cases, goldenCase{name: template.Manifest.Name, opts: projectOptions{Template: template.Manifest.Name}})
+	}
+
+	covered := func(name string) bool {
+		return slices.ContainsFunc(cases, func(c goldenCase) bool { return c.name == name })
+	}
+	for _, template := range templates {
+		genre := template.Manifest.Name
+		if template.Manifest.Genre != "" {
+			continue
+		}
+		var features []string
+		for _, layer := range layers {
+			if _, ok := layer.Genres[genre]; ok && !layer.Default {
+				features = append(features, layer.Name)
+			}
+		}
+
+		// Every non-empty subset of the features, in layer order
+		for mask := 1; mask < 1<<len(features); mask++ {
+			var with []string
+			for i, feature := range features {
+				if mask&(1<<i) != 0 {
+					with = append(with, feature)
+				}
+			}
+			composed, err := composeTemplate(genre, with, templates)
+			if err != nil {
+				t.Fatalf("%s with %s: %v", genre, strings.Join(with, ","), err)
+			}
+			if covered(composed.Manifest.Name) {
+				continue
+			}
+			cases = append(cases, goldenCase{name: composed.Manifest.Name, opts: projectOptions{Genre: genre, With: with}})
+		}
+	}
+	return cases
+}
+
+// fileTree lists every file below dir with a short hash of its content, one
+// "<hash>  <path>" line per file like sha256sum prints them
+func fileTree(t *testing.T, dir string) []byte {
+	var b bytes.Buffer
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		// The base copies repeat files the tree already lists
+		if err == nil && d.IsDir() && p == filepath.Join(dir, baseDirName) {
+			return fs.SkipDir
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		fmt.Fprintf(&b, "%s  %s\n", hex.EncodeToString(sum[:])[:16], filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// checkGolden compares got with the golden file, or rewrites it with -update
+func checkGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if os.IsNotExist(err) {
+		t.Fatalf("%s is missing, create it with: go test -run TestGenerateTemplates -update", golden)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+
+	// Report the lines that differ, a hash alone doesn't say much
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	var diff []string
+	for _, line := range wantLines {
+		if line != "" && !slices.Contains(gotLines, line) {
+			diff = append(diff, "- "+line)
+		}
+	}
+	for _, line := range gotLines {
+		if line != "" && !slices.Contains(wantLines, line) {
+			diff = append(diff, "+ "+line)
+		}
+	}
+	t.Errorf("generated files differ from %s (run with -update if the change is intended):\n%s", golden, strings.Join(diff, "\n"))
+}
+
+// checkStaleGolden reports golden files of templates that no longer exist, or
+// removes them with -update
+func checkStaleGolden(t *testing.T, cases []goldenCase) {
+	entries, err := os.ReadDir(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".txt")
+		if slices.ContainsFunc(cases, func(c goldenCase) bool { return c.name == name }) {
+			continue
+		}
+		golden := filepath.Join(goldenDir, entry.Name())
+		if *update {
+			if err := os.Remove(golden); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		t.Errorf("%s belongs to no template, remove it or run with -update", golden)
+	}
+}
+
+// bappaModuleDirs returns the directory of every bappa module available without
+// network access, by module path. They come from $BAPPACREATE_BAPPA_DIR when it is
+// set, and from the module cache entries of bappacreate's own dependencies otherwise.
+func bappaModuleDirs(t *testing.T) map[string]string {
+	dirs := map[string]string{}
+	if root := os.Getenv(bappaDirEnv); root != "" {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			t.Fatalf("%s: %v", bappaDirEnv, err)
+		}
+		for _, entry := range entries {
+			dir := filepath.Join(root, entry.Name())
+			data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+			if err != nil {
+				continue
+			}
+			if path := modfile.ModulePath(data); strings.HasPrefix(path, bappaModuleRoot+"/") {
+				dirs[path] = dir
+			}
+		}
+		return dirs
+	}
+
+	out := goCommand(t, ".", "", "list", "-m", "-e", "-f", "{{.Path}} {{.Dir}}", "all")
+	for _, line := range strings.Split(out, "\n") {
+		path, dir, ok := strings.Cut(line, " ")
+		if ok && dir != "" && strings.HasPrefix(path, bappaModuleRoot+"/") {
+			dirs[path] = dir
+		}
+	}
+	return dirs
+}
+
+// compileRequested reports whether the generated projects are to be compiled,
+// which needs the bappa modules and isn't done by a plain go test
+func compileRequested() bool {
+	return !testing.Short() && (os.Getenv(compileEnv) == "1" || os.Getenv(bappaDirEnv) != "")
+}
+
+// compileProject points the bappa modules of the project in dir at bappa with
+// replace directives, runs go vet in each of its modules and builds all of them
+// from dir. The game is never run, so no display or GPU is needed.
+func compileProject(t *testing.T, dir string, bappa map[string]string) {
+	if !compileRequested() {
+		t.Skipf("not compiling the project, set %s=1 to compile it", compileEnv)
+	}
+
+	var goMods []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.Name() == "go.mod" {
+			goMods = append(goMods, p)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing []string
+	for _, goMod := range goMods {
+		data, err := os.ReadFile(goMod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, err := modfile.Parse(goMod, data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, req := range file.Require {
+			path := req.Mod.Path
+			if strings.HasPrefix(path, bappaModuleRoot+"/") && bappa[path] == "" && !slices.Contains(missing, path) {
+				missing = append(missing, path)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		// Compiling was asked for, so passing without it would hide broken templates
+		t.Fatalf("cannot compile the project, %s not available offline: run 'go mod download' in the repository or set %s to a bappa checkout", strings.Join(missing, ", "), bappaDirEnv)
+	}
+
+	workspace := fileExists(filepath.Join(dir, "go.work"))
+	goFlags := "-mod=mod"
+	if workspace {
+		goFlags = "" // -mod can't be set in workspace mode
+	}
+	replaceModules(t, dir, bappa)
+
+	var moduleDirs []string
+	for _, module := range strings.Fields(goCommand(t, dir, goFlags, "list", "-m", "-f", "{{.Dir}}")) {
+		goCommand(t, module, goFlags, "vet", "./...")
+		rel, err := filepath.Rel(dir, module)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moduleDirs = append(moduleDirs, filepath.ToSlash(rel))
+	}
+
+	// Workspaces are built from their root, the way the project's instructions say
+	if !workspace {
+		goCommand(t, dir, goFlags, "build", "./...")
+		return
+	}
+	goCommand(t, dir, goFlags, append([]string{"build"}, workspacePackages(moduleDirs)...)...)
+}
+
+// replaceModules adds a replace directive for every module in dirs to the go.work
+// in dir, which applies to all of its modules, or else to the go.mod in dir.
+// `go mod edit -replace` would take the @ of module cache directories for a version.
+func replaceModules(t *testing.T, dir string, dirs map[string]string) {
+	name := filepath.Join(dir, "go.work")
+	data, err := os.ReadFile(name)
+	var syntax *modfile.FileSyntax
+	var addReplace func(path, dir string) error
+	switch {
+	case err == nil:
+		work, err := modfile.ParseWork(name, data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		syntax = work.Syntax
+		addReplace = func(path, dir string) error { return work.AddReplace(path, "", dir, "") }
+	case os.IsNotExist(err):
+		name = filepath.Join(dir, "go.mod")
+		if data, err = os.ReadFile(name); err != nil {
+			t.Fatal(err)
+		}
+		mod, err := modfile.Parse(name, data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		syntax = mod.Syntax
+		addReplace = func(path, dir string) error { return mod.AddReplace(path, "", dir, "") }
+	default:
+		t.Fatal(err)
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(dirs)) {
+		if err := addReplace(path, dirs[path]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(name, modfile.Format(syntax), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// goCommand runs the go command offline in dir and returns its output
+func goCommand(t *testing.T, dir, goFlags string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOPROXY=off", "GOTOOLCHAIN=local", "GOFLAGS="+goFlags)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go %s in %s: %v\n%s", strings.Join(args, " "), dir, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
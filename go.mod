@@ -22,6 +22,9 @@ require (
 	github.com/TheBitDrifter/bappa/tteokbokki v0.0.0-20250420132432-5606172c9a41
 	github.com/TheBitDrifter/bappa/warehouse v0.0.0-20250420132432-5606172c9a41
 	github.com/hajimehoshi/ebiten/v2 v2.8.7
+	golang.org/x/mod v0.24.0
+	golang.org/x/text v0.23.0
+	golang.org/x/tools v0.25.0
 )
 
 require (
@@ -40,5 +43,4 @@ require (
 	golang.org/x/image v0.25.0 // indirect
 	golang.org/x/sync v0.12.0 // indirect
 	golang.org/x/sys v0.25.0 // indirect
-	golang.org/x/text v0.23.0 // indirect
 )
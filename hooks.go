@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Hook is a step run in a project once it has been generated: a built-in step,
+// written as its name in JSON, or a shell command written as {"run": "<command>"}
+type Hook struct {
+	Step string `json:"step,omitempty"` // built-in step, see hookSteps
+	Run  string `json:"run,omitempty"`  // shell command run in the project directory
+}
+
+func (h Hook) String() string {
+	if h.Step != "" {
+		return h.Step
+	}
+	return h.Run
+}
+
+func (h *Hook) UnmarshalJSON(data []byte) error {
+	var step string
+	if err := json.Unmarshal(data, &step); err == nil {
+		*h = Hook{Step: step}
+		return nil
+	}
+	type plainHook Hook
+	return json.Unmarshal(data, (*plainHook)(h))
+}
+
+func (h Hook) MarshalJSON() ([]byte, error) {
+	if h.Step != "" {
+		return json.Marshal(h.Step)
+	}
+	type plainHook Hook
+	return json.Marshal(plainHook(h))
+}
+
+// hookSkipped is returned by a step with nothing to do, it is reported but isn't a failure
+type hookSkipped string
+
+func (s hookSkipped) Error() string { return string(s) }
+
+// hookRun is the generation hooks run after
+type hookRun struct {
+	dir     string // project directory
+	plan    *projectPlan
+	written []string // files this generation wrote, existing files may be the user's
+	merge   bool     // generated with --merge, into an existing project
+}
+
+// hookSteps are the built-in steps. Each returns what it did.
+var hookSteps = map[string]func(run *hookRun) (string, error){
+	"git-init":         gitInitHook,
+	"gitignore":        gitignoreHook,
+	"gofmt":            gofmtHook,
+	"tidy-all-modules": tidyHook,
+}
+
+// validateHooks checks that every hook is either a known step or a command
+func validateHooks(where string, hooks []Hook) error {
+	for _, hook := range hooks {
+		switch {
+		case (hook.Step == "") == (hook.Run == ""):
+			return fmt.Errorf("%s: a hook is either a step name or a {\"run\": \"<command>\"}", where)
+		case hook.Step != "" && hookSteps[hook.Step] == nil:
+			return fmt.Errorf("%s: unknown hook %q (available: %s)", where, hook.Step, strings.Join(hookStepNames(), ", "))
+		}
+	}
+	return nil
+}
+
+func hookStepNames() []string {
+	names := make([]string, 0, len(hookSteps))
+	for name := range hookSteps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// userConfig is the user's config file, e.g. ~/.config/bappacreate/config.json
+type userConfig struct {
+	Hooks []Hook `json:"hooks"` // run after the template's hooks in every generated project
+}
+
+// loadUserConfig reads the user's config file, which is optional
+func loadUserConfig() (*userConfig, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return &userConfig{}, nil
+	}
+	name := filepath.Join(configDir, "bappacreate", "config.json")
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &userConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config := &userConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if err := validateHooks(name, config.Hooks); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// untrustedCommands returns the shell commands among the hooks of a template from
+// --template-dir or --template-repo. Unlike those of the built-in templates and the
+// user's own, they only run with --allow-hooks.
+func untrustedCommands(manifest *TemplateManifest, templates templateOptions) []Hook {
+	if templates.Dir == "" && templates.Repo == "" {
+		return nil
+	}
+	var commands []Hook
+	for _, hook := range manifest.Hooks {
+		if hook.Run != "" {
+			commands = append(commands, hook)
+		}
+	}
+	return commands
+}
+
+// projectHooks returns the template's hooks followed by the user's, each hook once.
+// The untrusted ones are returned apart as blocked, unless the user's config has them too.
+func projectHooks(manifest *TemplateManifest, untrusted []Hook) (hooks, blocked []Hook, err error) {
+	config, err := loadUserConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, hook := range append(append([]Hook{}, manifest.Hooks...), config.Hooks...) {
+		switch {
+		case slices.Contains(hooks, hook) || slices.Contains(blocked, hook):
+		case slices.Contains(untrusted, hook) && !slices.Contains(config.Hooks, hook):
+			blocked = append(blocked, hook)
+		default:
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks, blocked, nil
+}
+
+// runHooks runs the plan's hooks in the project directory and reports the outcome
+// of each. A failed hook doesn't stop the others, they are counted instead.
+func runHooks(run *hookRun) (failed int) {
+	if len(run.plan.Hooks) == 0 && len(run.plan.BlockedHooks) == 0 {
+		return 0
+	}
+	fmt.Println("\nRunning hooks...")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	for _, hook := range run.plan.Hooks {
+		var detail string
+		var err error
+		if hook.Step != "" {
+			detail, err = hookSteps[hook.Step](run)
+		} else {
+			detail, err = commandHook(run, hook.Run)
+		}
+
+		var skipped hookSkipped
+		switch {
+		case errors.As(err, &skipped):
+			fmt.Fprintf(w, "  %s\tskipped, %s\n", hook, skipped)
+		case err != nil:
+			failed++
+			fmt.Fprintf(w, "  %s\tfailed: %s\n", hook, indentOutput(err.Error()))
+		default:
+			fmt.Fprintf(w, "  %s\t%s\n", hook, detail)
+		}
+	}
+	for _, hook := range run.plan.BlockedHooks {
+		fmt.Fprintf(w, "  %s\tskipped, the template's shell commands only run with --allow-hooks\n", hook)
+	}
+	return failed
+}
+
+// indentOutput indents the lines after the first of a command's output
+func indentOutput(s string) string {
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", "\n      ")
+}
+
+// runHookCommand runs a command in dir without streaming its output, which is
+// only shown when it fails
+func runHookCommand(cmd *exec.Cmd, dir string) error {
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(bytes.TrimSpace(out)) > 0 {
+			return fmt.Errorf("%v\n%s", err, out)
+		}
+		return err
+	}
+	return nil
+}
+
+// commandHook runs a custom hook with the shell. It gets the project's module path
+// and template in BAPPACREATE_MODULE and BAPPACREATE_TEMPLATE.
+func commandHook(run *hookRun, command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	cmd.Env = append(os.Environ(), "BAPPACREATE_MODULE="+run.plan.ModulePath, "BAPPACREATE_TEMPLATE="+run.plan.Template)
+	if err := runHookCommand(cmd, run.dir); err != nil {
+		return "", err
+	}
+	return "ok", nil
+}
+
+// gitInitHook creates a git repository, unless the project already is in one
+func gitInitHook(run *hookRun) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", hookSkipped("git is not installed")
+	}
+	if exec.Command("git", "-C", run.dir, "rev-parse", "--is-inside-work-tree").Run() == nil {
+		return "", hookSkipped("already in a git repository")
+	}
+	if err := runHookCommand(exec.Command("git", "init", "--quiet"), run.dir); err != nil {
+		return "", err
+	}
+	return "initialized an empty git repository", nil
+}
+
+// gitignoreHook writes a .gitignore for the binaries go build creates in the project
+func gitignoreHook(run *hookRun) (string, error) {
+	plan := run.plan
+	name := filepath.Join(run.dir, ".gitignore")
+	if fileExists(name) {
+		return "", hookSkipped(".gitignore already exists")
+	}
+
+	// go build names a binary after the last element of its module path
+	modules := plan.GoModules
+	if len(modules) == 0 {
+		modules = []string{"."}
+	}
+	var b strings.Builder
+	b.WriteString("# Binaries built with go build\n")
+	for _, module := range modules {
+		isMain := false
+		for _, file := range plan.Files {
+			isMain = isMain || file.Path == path.Join(module, "main.go")
+		}
+		switch {
+		case !isMain:
+		case module == ".":
+			fmt.Fprintf(&b, "/%s\n", path.Base(plan.ModulePath))
+		default:
+			fmt.Fprintf(&b, "/%s/%s\n", module, path.Base(module))
+		}
+	}
+	b.WriteString("*.exe\n*.test\n*.out\n\n# Editors and operating systems\n.idea/\n.vscode/\n.DS_Store\nThumbs.db\n")
+
+	if err := os.WriteFile(name, []byte(b.String()), 0644); err != nil {
+		return "", err
+	}
+	return "wrote .gitignore", nil
+}
+
+// gofmtHook formats the Go files this generation wrote, e.g. rendered .tmpl files.
+// Files that were already there are the user's and left alone.
+func gofmtHook(run *hookRun) (string, error) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		return "", hookSkipped("gofmt is not installed")
+	}
+	args := []string{"-l", "-w"}
+	for _, name := range run.written {
+		if path.Ext(name) == ".go" {
+			args = append(args, filepath.FromSlash(name))
+		}
+	}
+	if len(args) == 2 {
+		return "", hookSkipped("no Go files were written")
+	}
+
+	cmd := exec.Command("gofmt", args...)
+	cmd.Dir = run.dir
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%v\n%s", err, exitErr.Stderr)
+		}
+		return "", err
+	}
+	if formatted := strings.Fields(string(out)); len(formatted) > 0 {
+		return fmt.Sprintf("formatted %d file(s)", len(formatted)), nil
+	}
+	return "already formatted", nil
+}
+
+// tidyHook runs go mod tidy in every module of the project, like the client,
+// server and standalone modules of a netcode project
+func tidyHook(run *hookRun) (string, error) {
+	plan := run.plan
+	switch {
+	case plan.Offline:
+		return "", hookSkipped("go mod tidy needs network access (offline)")
+	case run.merge:
+		return "", hookSkipped("--merge leaves the existing modules alone")
+	}
+	modules := plan.GoModules
+	if len(modules) == 0 {
+		modules = []string{"."}
+	}
+
+	for _, module := range modules {
+		dir := filepath.Join(run.dir, filepath.FromSlash(module))
+		if err := runHookCommand(exec.Command("go", "mod", "tidy"), dir); err != nil {
+			return "", fmt.Errorf("in %s: %v", module, err)
+		}
+	}
+	return "tidied " + strings.Join(modules, ", "), nil
+}
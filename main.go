@@ -1,17 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"text/tabwriter"
 )
 
-//go:embed templates
+//go:embed templates templates/_layers
 var templateFS embed.FS
 
 // Define file types that should be copied as-is (not processed for replacements)
@@ -25,603 +28,500 @@ var binaryExtensions = map[string]bool{
 	".ogg":  true,
 }
 
-// Structure to define common files and where they should be copied to in each template
-type CommonFileMapping struct {
-	SourcePath      string
-	DestinationPath map[string]string // Map of template name → destination path within project
-}
-
-var templateImportPaths = map[string]string{
-	"platformer":            "github.com/TheBitDrifter/bappacreate/templates/platformer",
-	"platformer-split":      "github.com/TheBitDrifter/bappacreate/templates/platformer-split",
-	"platformer-ldtk":       "github.com/TheBitDrifter/bappacreate/templates/platformer-ldtk",
-	"platformer-split-ldtk": "github.com/TheBitDrifter/bappacreate/templates/platformer-split-ldtk",
-}
-
 // Common import path that will be replaced in all files
 var commonImportPattern = "github.com/TheBitDrifter/bappacreate/templates/common"
 
-var commonFiles = []CommonFileMapping{
-	// Core Systems
-	{
-		SourcePath: "templates/common/coresystems/gravitysystem.go",
-		DestinationPath: map[string]string{
-			"platformer":            "coresystems/gravitysystem.go",
-			"platformer-split":      "coresystems/gravitysystem.go",
-			"platformer-ldtk":       "coresystems/gravitysystem.go",
-			"platformer-split-ldtk": "coresystems/gravitysystem.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/coresystems/frictionsystem.go",
-		DestinationPath: map[string]string{
-			"platformer":            "coresystems/frictionsystem.go",
-			"platformer-split":      "coresystems/frictionsystem.go",
-			"platformer-ldtk":       "coresystems/frictionsystem.go",
-			"platformer-split-ldtk": "coresystems/frictionsystem.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/coresystems/player_movement_system.go",
-		DestinationPath: map[string]string{
-			"platformer":            "coresystems/player_movement_system.go",
-			"platformer-split":      "coresystems/player_movement_system.go",
-			"platformer-ldtk":       "coresystems/player_movement_system.go",
-			"platformer-split-ldtk": "coresystems/player_movement_system.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/coresystems/player_block_collision_system.go",
-		DestinationPath: map[string]string{
-			"platformer":            "coresystems/player_block_collision_system.go",
-			"platformer-split":      "coresystems/player_block_collision_system.go",
-			"platformer-ldtk":       "coresystems/player_block_collision_system.go",
-			"platformer-split-ldtk": "coresystems/player_block_collision_system.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/coresystems/player_platform_collision_system.go",
-		DestinationPath: map[string]string{
-			"platformer":            "coresystems/player_platform_collision_system.go",
-			"platformer-split":      "coresystems/player_platform_collision_system.go",
-			"platformer-ldtk":       "coresystems/player_platform_collision_system.go",
-			"platformer-split-ldtk": "coresystems/player_platform_collision_system.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/coresystems/on_ground_clearing_system.go",
-		DestinationPath: map[string]string{
-			"platformer":            "coresystems/on_ground_clearing_system.go",
-			"platformer-split":      "coresystems/on_ground_clearing_system.go",
-			"platformer-ldtk":       "coresystems/on_ground_clearing_system.go",
-			"platformer-split-ldtk": "coresystems/on_ground_clearing_system.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/coresystems/ignore_platform_clearing_system.go",
-		DestinationPath: map[string]string{
-			"platformer":            "coresystems/ignore_platform_clearing_system.go",
-			"platformer-split":      "coresystems/ignore_platform_clearing_system.go",
-			"platformer-ldtk":       "coresystems/ignore_platform_clearing_system.go",
-			"platformer-split-ldtk": "coresystems/ignore_platform_clearing_system.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/coresystems/common.go",
-		DestinationPath: map[string]string{
-			"platformer":            "coresystems/common.go",
-			"platformer-split":      "coresystems/common.go",
-			"platformer-ldtk":       "coresystems/common.go",
-			"platformer-split-ldtk": "coresystems/common.go",
-		},
-	},
-	// Client Systems
-	{
-		SourcePath: "templates/common/clientsystems/camera_follower_system.go",
-		DestinationPath: map[string]string{
-			"platformer":            "clientsystems/camera_follower_system.go",
-			"platformer-split":      "clientsystems/camera_follower_system.go",
-			"platformer-ldtk":       "clientsystems/camera_follower_system.go",
-			"platformer-split-ldtk": "clientsystems/camera_follower_system.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/clientsystems/player_animation_system.go",
-		DestinationPath: map[string]string{
-			"platformer":            "clientsystems/player_animation_system.go",
-			"platformer-split":      "clientsystems/player_animation_system.go",
-			"platformer-ldtk":       "clientsystems/player_animation_system.go",
-			"platformer-split-ldtk": "clientsystems/player_animation_system.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/clientsystems/player_sound_system.go",
-		DestinationPath: map[string]string{
-			"platformer":            "clientsystems/player_sound_system.go",
-			"platformer-split":      "clientsystems/player_sound_system.go",
-			"platformer-ldtk":       "clientsystems/player_sound_system.go",
-			"platformer-split-ldtk": "clientsystems/player_sound_system.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/clientsystems/musicsystem.go",
-		DestinationPath: map[string]string{
-			"platformer":            "clientsystems/musicsystem.go",
-			"platformer-split":      "clientsystems/musicsystem.go",
-			"platformer-ldtk":       "clientsystems/musicsystem.go",
-			"platformer-split-ldtk": "clientsystems/musicsystem.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/clientsystems/collision_player_transfer_system.go",
-		DestinationPath: map[string]string{
-			"platformer":            "clientsystems/collision_player_transfer_system.go",
-			"platformer-split":      "clientsystems/collision_player_transfer_system.go",
-			"platformer-ldtk":       "clientsystems/collision_player_transfer_system.go",
-			"platformer-split-ldtk": "clientsystems/collision_player_transfer_system.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/clientsystems/scene_deactivation_system.go",
-		DestinationPath: map[string]string{
-			"platformer-split":      "clientsystems/scene_deactivation_system.go",
-			"platformer-split-ldtk": "clientsystems/scene_deactivation_system.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/clientsystems/common.go",
-		DestinationPath: map[string]string{
-			"platformer":            "clientsystems/common.go",
-			"platformer-split":      "clientsystems/common.go",
-			"platformer-ldtk":       "clientsystems/common.go",
-			"platformer-split-ldtk": "clientsystems/common.go",
-		},
-	},
-	// Components
-	{
-		SourcePath: "templates/common/components/components.go",
-		DestinationPath: map[string]string{
-			"platformer":            "components/components.go",
-			"platformer-split":      "components/components.go",
-			"platformer-ldtk":       "components/components.go",
-			"platformer-split-ldtk": "components/components.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/components/tags.go",
-		DestinationPath: map[string]string{
-			"platformer":            "components/tags.go",
-			"platformer-split":      "components/tags.go",
-			"platformer-ldtk":       "components/tags.go",
-			"platformer-split-ldtk": "components/tags.go",
-		},
-	},
-	// Other shared code
-	{
-		SourcePath: "templates/common/animations/animations.go",
-		DestinationPath: map[string]string{
-			"platformer":            "animations/animations.go",
-			"platformer-split":      "animations/animations.go",
-			"platformer-ldtk":       "animations/animations.go",
-			"platformer-split-ldtk": "animations/animations.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/sounds/sounds.go",
-		DestinationPath: map[string]string{
-			"platformer":            "sounds/sounds.go",
-			"platformer-split":      "sounds/sounds.go",
-			"platformer-ldtk":       "sounds/sounds.go",
-			"platformer-split-ldtk": "sounds/sounds.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/actions/actions.go",
-		DestinationPath: map[string]string{
-			"platformer":            "actions/actions.go",
-			"platformer-split":      "actions/actions.go",
-			"platformer-ldtk":       "actions/actions.go",
-			"platformer-split-ldtk": "actions/actions.go",
-		},
-	},
-	// Render Systems
-	{
-		SourcePath: "templates/common/rendersystems/common.go",
-		DestinationPath: map[string]string{
-			"platformer":            "rendersystems/common.go",
-			"platformer-split":      "rendersystems/common.go",
-			"platformer-ldtk":       "rendersystems/common.go",
-			"platformer-split-ldtk": "rendersystems/common.go",
-		},
-	},
-	{
-		SourcePath: "templates/common/rendersystems/player_camera_prio_system.go",
-		DestinationPath: map[string]string{
-			"platformer-split":      "rendersystems/player_camera_prio_system.go",
-			"platformer-split-ldtk": "rendersystems/player_camera_prio_system.go",
-		},
-	},
-}
-
-// Import path mappings - defines how to transform component imports for each template
-var importPathMappings = map[string]map[string]string{
-	"platformer": {
-		"github.com/TheBitDrifter/bappacreate/templates/common/components":    "github.com/TheBitDrifter/bappacreate/templates/platformer/components",
-		"github.com/TheBitDrifter/bappacreate/templates/common/actions":       "github.com/TheBitDrifter/bappacreate/templates/platformer/actions",
-		"github.com/TheBitDrifter/bappacreate/templates/common/animations":    "github.com/TheBitDrifter/bappacreate/templates/platformer/animations",
-		"github.com/TheBitDrifter/bappacreate/templates/common/sounds":        "github.com/TheBitDrifter/bappacreate/templates/platformer/sounds",
-		"github.com/TheBitDrifter/bappacreate/templates/common/coresystems":   "github.com/TheBitDrifter/bappacreate/templates/platformer/coresystems",
-		"github.com/TheBitDrifter/bappacreate/templates/common/clientsystems": "github.com/TheBitDrifter/bappacreate/templates/platformer/clientsystems",
-		"github.com/TheBitDrifter/bappacreate/templates/common/rendersystems": "github.com/TheBitDrifter/bappacreate/templates/platformer/rendersystems",
-	},
-	"platformer-split": {
-		"github.com/TheBitDrifter/bappacreate/templates/common/components":    "github.com/TheBitDrifter/bappacreate/templates/platformer-split/components",
-		"github.com/TheBitDrifter/bappacreate/templates/common/actions":       "github.com/TheBitDrifter/bappacreate/templates/platformer-split/actions",
-		"github.com/TheBitDrifter/bappacreate/templates/common/animations":    "github.com/TheBitDrifter/bappacreate/templates/platformer-split/animations",
-		"github.com/TheBitDrifter/bappacreate/templates/common/sounds":        "github.com/TheBitDrifter/bappacreate/templates/platformer-split/sounds",
-		"github.com/TheBitDrifter/bappacreate/templates/common/coresystems":   "github.com/TheBitDrifter/bappacreate/templates/platformer-split/coresystems",
-		"github.com/TheBitDrifter/bappacreate/templates/common/clientsystems": "github.com/TheBitDrifter/bappacreate/templates/platformer-split/clientsystems",
-		"github.com/TheBitDrifter/bappacreate/templates/common/rendersystems": "github.com/TheBitDrifter/bappacreate/templates/platformer-split/rendersystems",
-	},
-	"platformer-ldtk": {
-		"github.com/TheBitDrifter/bappacreate/templates/common/components":    "github.com/TheBitDrifter/bappacreate/templates/platformer-ldtk/components",
-		"github.com/TheBitDrifter/bappacreate/templates/common/actions":       "github.com/TheBitDrifter/bappacreate/templates/platformer-ldtk/actions",
-		"github.com/TheBitDrifter/bappacreate/templates/common/animations":    "github.com/TheBitDrifter/bappacreate/templates/platformer-ldtk/animations",
-		"github.com/TheBitDrifter/bappacreate/templates/common/sounds":        "github.com/TheBitDrifter/bappacreate/templates/platformer-ldtk/sounds",
-		"github.com/TheBitDrifter/bappacreate/templates/common/coresystems":   "github.com/TheBitDrifter/bappacreate/templates/platformer-ldtk/coresystems",
-		"github.com/TheBitDrifter/bappacreate/templates/common/clientsystems": "github.com/TheBitDrifter/bappacreate/templates/platformer-ldtk/clientsystems",
-		"github.com/TheBitDrifter/bappacreate/templates/common/rendersystems": "github.com/TheBitDrifter/bappacreate/templates/platformer-ldtk/rendersystems",
-	},
-	"platformer-split-ldtk": {
-		"github.com/TheBitDrifter/bappacreate/templates/common/components":    "github.com/TheBitDrifter/bappacreate/templates/platformer-split-ldtk/components",
-		"github.com/TheBitDrifter/bappacreate/templates/common/actions":       "github.com/TheBitDrifter/bappacreate/templates/platformer-split-ldtk/actions",
-		"github.com/TheBitDrifter/bappacreate/templates/common/animations":    "github.com/TheBitDrifter/bappacreate/templates/platformer-split-ldtk/animations",
-		"github.com/TheBitDrifter/bappacreate/templates/common/sounds":        "github.com/TheBitDrifter/bappacreate/templates/platformer-split-ldtk/sounds",
-		"github.com/TheBitDrifter/bappacreate/templates/common/coresystems":   "github.com/TheBitDrifter/bappacreate/templates/platformer-split-ldtk/coresystems",
-		"github.com/TheBitDrifter/bappacreate/templates/common/clientsystems": "github.com/TheBitDrifter/bappacreate/templates/platformer-split-ldtk/clientsystems",
-		"github.com/TheBitDrifter/bappacreate/templates/common/rendersystems": "github.com/TheBitDrifter/bappacreate/templates/platformer-split-ldtk/rendersystems",
-	},
-}
-
 func main() {
-	if len(os.Args) < 2 {
-		printUsage()
-		os.Exit(1)
-	}
-
-	projectName := os.Args[1]
-	template := "topdown" // Default template
-
-	// Check if user specified a template
-	if len(os.Args) >= 4 && os.Args[2] == "--template" {
-		template = os.Args[3]
-	}
-
-	createProject(projectName, template)
+	os.Exit(runCLI(os.Args[1:]))
 }
 
+// printUsage is the help text of the new command
 func printUsage() {
 	fmt.Println("Bappa Game Template Generator")
 	fmt.Println("===============================")
-	fmt.Println("Usage: bappacreate username/project-name [--template <template-name>]")
+	fmt.Println("Usage: bappacreate new [flags] username/project-name")
+	fmt.Println("       bappacreate new [flags] --module <module/path> [project-dir]")
 	fmt.Println()
 	fmt.Println("This tool creates a new Bappa game project with the specified name.")
 	fmt.Println("The username/ prefix is used to create the proper Go module path.")
-	fmt.Println("Example: bappacreate johndoe/my-awesome-game --template platformer")
+	fmt.Println("Use --module to pick any other module path, the directory then defaults")
+	fmt.Println("to the last element of the module path.")
+	fmt.Println("Without a project name, in a terminal, it asks for the settings instead.")
+	fmt.Println("Example: bappacreate new johndoe/my-awesome-game --template platformer")
+	fmt.Println("Example: bappacreate new --module git.example.com/games/space space-game")
+	fmt.Println("Example: bappacreate new johndoe/dungeon --genre topdown --with split,ldtk")
 	fmt.Println()
-	fmt.Println("Available templates:")
-	fmt.Println("  platformer      - A simple platformer game")
-	fmt.Println("  platformer-split - A platformer game with split-screen co-op support")
-	fmt.Println("  platformer-ldtk - A platformer game with LDtk level editor support")
-	fmt.Println("  platformer-split-ldtk - A platformer game with split-screen co-op and LDtk support")
-	fmt.Println("  platformer-netcode - A networked platformer game with client/server architecture")
-	fmt.Println("  topdown         - A top-down perspective game (default)")
-	fmt.Println("  topdown-split   - A top-down game with split-screen co-op support")
-	fmt.Println("  sandbox         - An open sandbox game environment")
+	printTemplateList()
 }
 
-func createProject(projectName, templateName string) {
-	// Check if the project name contains a username
-	parts := strings.Split(projectName, "/")
-	if len(parts) != 2 {
-		fmt.Println("Error: Project name must be in the format 'username/project-name'")
-		os.Exit(1)
+func printTemplateList() {
+	templates, cleanup, err := loadTemplates(templateOptions{})
+	if err != nil {
+		fmt.Printf("Error reading templates: %v\n", err)
+		return
 	}
+	defer cleanup()
 
-	username := parts[0]
-	projectNameOnly := parts[1]
-
-	// Clean the project name for Go module naming
-	moduleName := strings.ToLower(strings.ReplaceAll(projectNameOnly, " ", "-"))
+	fmt.Println("Available templates:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, template := range templates {
+		manifest := template.Manifest
+		description := manifest.Description
+		if manifest.Name == defaultTemplate {
+			description += " (default)"
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", manifest.Name, description)
+	}
+	w.Flush()
 
-	// Create project directory (just the project part, not with username)
-	err := os.MkdirAll(projectNameOnly, 0755)
+	layers, err := loadLayers()
 	if err != nil {
-		fmt.Printf("Error creating directory: %v\n", err)
-		os.Exit(1)
+		fmt.Printf("Error reading features: %v\n", err)
+		return
 	}
+	fmt.Println()
+	fmt.Println("Features for --with:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, layer := range layers {
+		name := layer.Name
+		if layer.Default {
+			name += " (default)"
+		}
+		fmt.Fprintf(w, "  %s\t%s (%s)\n", name, layer.Description, strings.Join(layer.genreNames(), ", "))
+	}
+	w.Flush()
+}
 
-	fmt.Printf("Creating new Bappa game project: %s (using %s template)\n", projectNameOnly, templateName)
+// projectOptions are the settings of a `new` invocation
+type projectOptions struct {
+	Name       string   // username/project-name, or the project directory when Module is set
+	Module     string   // module path the template imports are rewritten to
+	Author     string   // overrides the author derived from the module path
+	Title      string   // overrides the window title derived from the project name
+	Template   string   // "" picks the default template
+	Genre      string   // base template the With features are added to, instead of Template
+	With       []string // feature layers
+	Templates  templateOptions
+	DryRun     bool // print the plan instead of generating
+	JSON       bool // print the dry run plan as JSON
+	Force      bool // overwrite files in an existing project directory
+	Merge      bool // only write files missing from an existing project directory
+	NoHooks    bool // don't run the template's and the user's hooks
+	AllowHooks bool // run the shell commands of a --template-dir or --template-repo template
+	Deps       dependencyOptions
+
+	Resolution *Resolution                      // overrides the template's resolution
+	Confirm    func(*projectPlan) (bool, error) // asked before anything is written, nil to not ask
+}
 
-	// The template import path that will be replaced
-	templateImportPath := fmt.Sprintf("github.com/TheBitDrifter/bappacreate/templates/%s", templateName)
+// writeMode controls how generation treats files that already exist
+type writeMode int
 
-	// Make sure the template exists
-	templatePath := filepath.Join("templates", templateName)
+const (
+	writeNew   writeMode = iota // the project directory must be missing or empty
+	writeForce                  // existing files are overwritten
+	writeMerge                  // existing files are left alone and reported when they differ
+)
 
-	_, err = fs.Stat(templateFS, templatePath)
-	if err != nil {
-		fmt.Printf("Error: Template '%s' not found\n", templateName, err)
-		os.Exit(1)
-	}
+// writeResult summarizes what writeProject did
+type writeResult struct {
+	Written   []string
+	Unchanged []string
+	Conflicts []string
+	Contents  map[string][]byte // content of every planned file, see lockfile
+}
 
-	if templateName == "platformer-netcode" {
-		handleNetcodeTemplate(projectNameOnly, username, moduleName)
-		return
+func (o projectOptions) writeMode() writeMode {
+	switch {
+	case o.Force:
+		return writeForce
+	case o.Merge:
+		return writeMerge
+	default:
+		return writeNew
 	}
+}
 
-	// Create additional directories that might not be in the templates
-	os.MkdirAll(filepath.Join(projectNameOnly, "assets", "images"), 0755)
-	os.MkdirAll(filepath.Join(projectNameOnly, "assets", "sounds"), 0755)
+func createProject(opts projectOptions) error {
+	templateName := opts.Template
+
+	names, err := resolveProjectNames(opts.Name, opts.Module)
+	if err != nil {
+		return err
+	}
+	if opts.Author != "" {
+		names.Author = opts.Author
+	}
+	if opts.Title != "" {
+		names.Title = opts.Title
+	}
+	projectNameOnly := names.Dir
+	modulePath := names.ModulePath
 
-	// Create additional directories for split-screen co-op templates
-	if strings.HasSuffix(templateName, "-split") {
-		os.MkdirAll(filepath.Join(projectNameOnly, "players"), 0755)
-		os.MkdirAll(filepath.Join(projectNameOnly, "splitscreen"), 0755)
+	// Make sure the template exists
+	templates, cleanup, err := loadTemplates(opts.Templates)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	template, err := selectTemplate(opts.Template, opts.Genre, opts.With, templates)
+	if err != nil {
+		return err
 	}
+	manifest := template.Manifest
+	templateName = manifest.Name
 
-	// Process all template files
-	err = fs.WalkDir(templateFS, templatePath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
+	mode := opts.writeMode()
+	if !opts.DryRun {
+		if err := checkProjectDir(projectNameOnly, mode); err != nil {
 			return err
 		}
+	}
 
-		// Skip the root template directory itself
-		if path == templatePath {
-			return nil
+	plan, err := buildPlan(manifest, template.Source, names, opts.Deps)
+	if err != nil {
+		return fmt.Errorf("planning project: %v", err)
+	}
+	if opts.Resolution != nil {
+		plan.Resolution = *opts.Resolution
+	}
+	if !opts.NoHooks {
+		var untrusted []Hook
+		if !opts.AllowHooks {
+			untrusted = untrustedCommands(manifest, opts.Templates)
 		}
-
-		// Create relative path from the template directory
-		relPath, err := filepath.Rel(templatePath, path)
-		if err != nil {
+		if plan.Hooks, plan.BlockedHooks, err = projectHooks(manifest, untrusted); err != nil {
 			return err
 		}
+	}
 
-		// Create the target path in the new project using projectNameOnly
-		targetPath := filepath.Join(projectNameOnly, relPath)
-
-		// Create directories
-		if d.IsDir() {
-			return os.MkdirAll(targetPath, 0755)
+	if opts.DryRun {
+		if opts.JSON {
+			return printPlanJSON(os.Stdout, plan)
 		}
-
-		// Check if this file should be skipped because it will be created from common template
-		skipFile := false
-		for _, mapping := range commonFiles {
-			destPath, exists := mapping.DestinationPath[templateName]
-			if exists && relPath == destPath {
-				skipFile = true
-				break
-			}
+		printPlan(os.Stdout, plan)
+		return nil
+	}
+	if opts.Confirm != nil {
+		ok, err := opts.Confirm(plan)
+		if err != nil || !ok {
+			return err
 		}
+	}
 
-		if skipFile {
-			fmt.Printf("Skipping: %s (will be created from common template)\n", targetPath)
-			return nil
-		}
+	fmt.Printf("Creating new Bappa game project: %s (using %s template)\n", projectNameOnly, templateName)
 
-		// Handle the file appropriately
-		ext := strings.ToLower(filepath.Ext(path))
+	// A merged project keeps its module, only new projects get one
+	_, statErr := os.Stat(filepath.Join(projectNameOnly, "go.mod"))
+	hasGoMod := statErr == nil
 
-		if binaryExtensions[ext] {
-			// Copy binary files directly
-			return copyBinaryFile(path, targetPath)
-		} else {
-			// Process text files with replacement
-			return processTextFile(path, targetPath, username, moduleName, templateImportPath, templateName)
-		}
-	})
+	// Everything is generated in a staging directory that replaces the
+	// project directory only after every step has succeeded
+	staging, err := newStagingDir(projectNameOnly)
 	if err != nil {
-		fmt.Printf("Error creating project: %v\n", err)
-		os.Exit(1)
+		return err
 	}
+	defer staging.Cleanup()
 
-	// Copy common files that should replace template-specific ones
-	fmt.Println("\nProcessing common template files...")
-	err = copyCommonFiles(projectNameOnly, templateName, username, moduleName)
+	result, err := writeProject(plan, staging.Dir, mode)
 	if err != nil {
-		fmt.Printf("Error processing common files: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("creating project: %v", err)
 	}
 
-	// Calculate the full module path with username
-	modulePath := fmt.Sprintf("github.com/%s/%s", username, moduleName)
+	if mode == writeMerge {
+		printMergeResult(result)
+	}
+
+	// The lockfile lets `upgrade` bring later template fixes into the project
+	lock, err := newLockfile(opts, plan, result.Contents)
+	if err != nil {
+		return err
+	}
+	if err := lock.write(staging.Dir); err != nil {
+		return fmt.Errorf("writing %s: %v", lockfileName, err)
+	}
+	if err := writeBase(staging.Dir, plan, result.Contents); err != nil {
+		return fmt.Errorf("writing %s: %v", baseDirName, err)
+	}
 
 	// Initialize Go module and dependencies
-	initGoModule(projectNameOnly, modulePath)
+	switch {
+	case mode == writeMerge && hasGoMod:
+		fmt.Println("\nKeeping the existing go.mod")
+	case len(plan.GoModules) > 0:
+		fmt.Println("\nThe template's modules pin their own dependencies, skipping go commands")
+	case plan.Offline:
+		fmt.Println("\nWrote go.mod with pinned dependencies, skipping go commands (offline)")
+	default:
+		err = initGoModule(staging.Dir, modulePath, plan.Modules, opts.Deps.version())
+	}
+	if err != nil {
+		return fmt.Errorf("setting up Go module: %v (no files were written)", err)
+	}
+
+	if err := staging.Commit(); err != nil {
+		return err
+	}
+	// Hooks run in the project itself, a failed hook leaves it in place
+	failedHooks := runHooks(&hookRun{dir: projectNameOnly, plan: plan, written: result.Written, merge: mode == writeMerge})
+
+	if mode == writeMerge {
+		fmt.Printf("\nSuccessfully merged %s template into: %s\n", templateName, projectNameOnly)
+	} else {
+		fmt.Printf("\nSuccessfully created Bappa game project: %s\n", projectNameOnly)
+	}
+	if manifest.HasTag("netcode") {
+		printNetcodeInstructions(projectNameOnly, plan.GoModules)
+	} else {
+		fmt.Printf("\nTo run your game:\n")
+		fmt.Printf("  cd %s\n", projectNameOnly)
+		if !plan.Offline {
+			fmt.Printf("  go mod tidy\n")
+		}
+		fmt.Printf("  go run .\n")
+	}
 
-	fmt.Printf("\nSuccessfully created Bappa game project: %s\n", projectNameOnly)
-	fmt.Printf("\nTo run your game:\n")
-	fmt.Printf("  cd %s\n", projectNameOnly)
-	fmt.Printf("  go mod tidy\n")
-	fmt.Printf("  go run .\n")
+	if failedHooks > 0 {
+		return fmt.Errorf("%d hook(s) failed, see above", failedHooks)
+	}
+	return nil
 }
 
-// Copy common template files to the project with appropriate processing
-func copyCommonFiles(projectDir, templateName, username, moduleName string) error {
-	// Full project import path
-	projectImportPath := fmt.Sprintf("github.com/%s/%s", username, moduleName)
+// printNetcodeInstructions explains how to run the separate modules of a netcode
+// project, and how to build all of them from its root
+func printNetcodeInstructions(projectDir string, modules []string) {
+	fmt.Println("\nTo run networked, start the server and then the client in another terminal:")
+	fmt.Printf("  cd %s/server\n", projectDir)
+	fmt.Println("  go run .")
+	fmt.Println()
+	fmt.Printf("  cd %s/client\n", projectDir)
+	fmt.Println("  go run .")
+	fmt.Println("\nTo run single player/standalone:")
+	fmt.Printf("  cd %s/standalone\n", projectDir)
+	fmt.Println("  go run .")
+	fmt.Println("\nThe generated go.work ties the modules together. To build all of them:")
+	fmt.Printf("  cd %s\n", projectDir)
+	fmt.Printf("  go build %s\n", strings.Join(workspacePackages(modules), " "))
+}
 
-	for _, mapping := range commonFiles {
-		// Check if this template needs this file
-		destPath, exists := mapping.DestinationPath[templateName]
-		if !exists {
-			continue // Skip files not needed for this template
-		}
+// checkProjectDir refuses to generate into an existing, non-empty directory unless mode allows it
+func checkProjectDir(projectDir string, mode writeMode) error {
+	entries, err := os.ReadDir(projectDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("checking project directory: %v", err)
+	}
+	if len(entries) == 0 || mode != writeNew {
+		return nil
+	}
+	return fmt.Errorf("directory '%s' already exists and is not empty (use --force to overwrite it or --merge to only add missing files)", projectDir)
+}
 
-		// Full path in the project
-		fullDestPath := filepath.Join(projectDir, destPath)
+// writeProject creates the directories and files listed in plan inside dir.
+// Progress is reported using the plan's project directory.
+func writeProject(plan *projectPlan, dir string, mode writeMode) (*writeResult, error) {
+	result := &writeResult{Contents: map[string][]byte{}}
 
-		// Ensure the directory exists
-		if err := os.MkdirAll(filepath.Dir(fullDestPath), 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %v", filepath.Dir(fullDestPath), err)
+	for _, planDir := range plan.Directories {
+		targetPath := filepath.Join(dir, filepath.FromSlash(planDir))
+		if err := os.MkdirAll(targetPath, 0755); err != nil {
+			return nil, err
 		}
+	}
 
-		// Read the source file from the common template
-		content, err := templateFS.ReadFile(mapping.SourcePath)
+	for _, skipped := range plan.Skipped {
+		fmt.Printf("Skipping: %s (will be created from common template)\n", filepath.Join(plan.ProjectDir, filepath.FromSlash(skipped.Path)))
+	}
+
+	var errs []error
+	printedCommonHeader := false
+	for _, file := range plan.Files {
+		targetPath := filepath.Join(dir, filepath.FromSlash(file.Path))
+		displayPath := filepath.Join(plan.ProjectDir, filepath.FromSlash(file.Path))
+
+		content, err := renderFile(plan, file)
 		if err != nil {
-			// Check if file exists in template-specific location instead
-			templateSpecificPath := strings.Replace(mapping.SourcePath, "templates/common", "templates/"+templateName, 1)
-			fmt.Printf("Common file not found, trying template-specific file: %s\n", templateSpecificPath)
-			content, err = templateFS.ReadFile(templateSpecificPath)
-			if err != nil {
-				return fmt.Errorf("failed to read file %s: %v", mapping.SourcePath, err)
+			errs = append(errs, err)
+			continue
+		}
+		result.Contents[file.Path] = content
+
+		if mode == writeMerge {
+			existing, err := os.ReadFile(targetPath)
+			if err == nil {
+				if bytes.Equal(existing, content) {
+					result.Unchanged = append(result.Unchanged, file.Path)
+				} else {
+					result.Conflicts = append(result.Conflicts, file.Path)
+				}
+				continue
+			}
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+				continue
 			}
 		}
 
-		// Process the content
-		processedContent := processCommonFile(string(content), mapping.SourcePath, destPath, templateName, projectImportPath)
+		switch {
+		case file.CommonFile != "":
+			if !printedCommonHeader {
+				// Common files replace template-specific ones
+				fmt.Println("\nProcessing common template files...")
+				printedCommonHeader = true
+			}
+			fmt.Printf("  Created: %s\n", displayPath)
+		case file.Binary:
+			fmt.Println("Copying asset:", displayPath)
+		default:
+			fmt.Println("Creating:", displayPath)
+		}
 
-		// Write the processed content
-		if err := os.WriteFile(fullDestPath, []byte(processedContent), 0644); err != nil {
-			return fmt.Errorf("failed to write file %s: %v", fullDestPath, err)
+		// Ensure the directory exists
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create directory %s: %v", filepath.Dir(displayPath), err))
+			continue
 		}
+		if err := os.WriteFile(targetPath, content, 0644); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write file %s: %v", displayPath, err))
+			continue
+		}
+		result.Written = append(result.Written, file.Path)
+	}
 
-		fmt.Printf("  Created: %s\n", fullDestPath)
+	// Every file is attempted so all failures are reported at once
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
+	return result, nil
+}
 
-	return nil
+func printMergeResult(result *writeResult) {
+	fmt.Printf("\nMerged project: %d file(s) added, %d unchanged, %d conflict(s)\n",
+		len(result.Written), len(result.Unchanged), len(result.Conflicts))
+	if len(result.Conflicts) == 0 {
+		return
+	}
+	fmt.Println("These files differ from the template and were left untouched:")
+	for _, conflict := range result.Conflicts {
+		fmt.Printf("  %s\n", conflict)
+	}
 }
 
-// Process a common file's content to make it template-specific
-func processCommonFile(content, sourcePath, destPath, templateName, projectImportPath string) string {
-	// 1. Determine target package name from destination
-	destDir := filepath.Dir(destPath)
-	packageName := filepath.Base(destDir) // E.g., "coresystems" from "coresystems/gravitysystem.go"
-
-	// 2. Replace package declaration
-	packagePattern := regexp.MustCompile(`(?m)^package\s+\w+`)
-	content = packagePattern.ReplaceAllString(content, "package "+packageName)
-
-	// 3. Replace imports for this specific template
-	templateImportPath := templateImportPaths[templateName]
-
-	// First replace any imports from common to template-specific paths
-	templateSpecificMappings, hasMappings := importPathMappings[templateName]
-	if hasMappings {
-		for commonPath, tempPath := range templateSpecificMappings {
-			content = strings.ReplaceAll(content, commonPath, tempPath)
+// renderFile returns the content file will have in the generated project
+func renderFile(plan *projectPlan, file plannedFile) ([]byte, error) {
+	switch {
+	case file.Generated != "":
+		return file.content, nil
+	case file.CommonFile != "":
+		// The source is the template-specific file when the common one is missing
+		content, err := readSource(plan, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %v", file.Source, err)
 		}
+		return processCommonFile(content, file.Source, file.Path, plan.manifest, plan.ModulePath)
+	case file.Binary:
+		// Copy binary files directly
+		return fs.ReadFile(file.fsys, file.name)
+	default:
+		// Process text files with replacement
+		return processTextFile(plan, file)
 	}
+}
 
-	// Common path replacement fallback
-	content = strings.ReplaceAll(content, commonImportPattern, templateImportPath)
-
-	// 4. Replace template imports with final project imports
-	content = strings.ReplaceAll(content, templateImportPath, projectImportPath)
-
-	return content
+// Process a common file's content to make it template-specific
+func processCommonFile(content []byte, sourcePath, destPath string, manifest *TemplateManifest, projectImportPath string) ([]byte, error) {
+	// The package is named after the destination directory,
+	// e.g. "coresystems" from "coresystems/gravitysystem.go"
+	packageName := path.Base(path.Dir(destPath))
+	if path.Ext(destPath) != ".go" {
+		return []byte(rewriteTemplateImports(string(content), manifest, projectImportPath)), nil
+	}
+	return rewriteGoSource(sourcePath, content, manifest, projectImportPath, packageName)
 }
 
-func copyBinaryFile(sourcePath, targetPath string) error {
-	fmt.Println("Copying asset:", targetPath)
+// rewriteTemplateImports points every mention of the template and common packages
+// at the project. It is only used for non-Go files, Go files go through rewriteGoSource.
+func rewriteTemplateImports(content string, manifest *TemplateManifest, projectImportPath string) string {
+	builtinPath := builtinImportPath(manifest.Name)
 
-	// Read binary file
-	data, err := templateFS.ReadFile(sourcePath)
-	if err != nil {
-		return err
+	// First, handle any imports of inherited common packages
+	for commonPath, tempPath := range manifest.commonImportMappings() {
+		content = strings.ReplaceAll(content, commonPath, tempPath)
 	}
 
-	// Ensure the directory exists
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-		return err
-	}
+	// Common import path replacement
+	content = strings.ReplaceAll(content, commonImportPattern, builtinPath)
 
-	// Write to target path
-	return os.WriteFile(targetPath, data, 0644)
+	// Then replace all template imports with project imports
+	content = strings.ReplaceAll(content, builtinPath, projectImportPath)
+	return strings.ReplaceAll(content, manifest.SourceImportPath(), projectImportPath)
 }
 
-func processTextFile(sourcePath, targetPath, username, moduleName, templateImportPath, templateName string) error {
-	fmt.Println("Creating:", targetPath)
+// processTextFile renders file if it is a .tmpl file and then points its imports at the project
+func processTextFile(plan *projectPlan, file plannedFile) ([]byte, error) {
+	sourcePath, targetPath := file.Source, file.Path
+	projectImportPath, manifest := plan.ModulePath, plan.manifest
 
-	// Calculate the new import path including username
-	projectImportPath := fmt.Sprintf("github.com/%s/%s", username, moduleName)
-
-	// Read file content
-	content, err := templateFS.ReadFile(sourcePath)
+	// Read file content, rendering templates before their imports are rewritten
+	content, err := readSource(plan, file)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fileContent := string(content)
-
-	// First, handle any imports from common to template-specific paths
-	templateSpecificMappings, hasMappings := importPathMappings[templateName]
-	if hasMappings {
-		for commonPath, tempPath := range templateSpecificMappings {
-			fileContent = strings.ReplaceAll(fileContent, commonPath, tempPath)
-		}
+	// Go files only get their import specs rewritten
+	if path.Ext(targetPath) == ".go" {
+		return rewriteGoSource(sourcePath, content, manifest, projectImportPath, "")
 	}
 
-	// Common import path replacement
-	fileContent = strings.ReplaceAll(fileContent, commonImportPattern, templateImportPath)
-
-	// Then replace all template imports with project imports
-	fileContent = strings.ReplaceAll(fileContent, templateImportPath, projectImportPath)
+	fileContent := rewriteTemplateImports(string(content), manifest, projectImportPath)
 
 	// Special handling for go.mod file
-	if filepath.Base(targetPath) == "go.mod" {
+	if path.Base(targetPath) == "go.mod" {
+		// Nested modules live below the project module, e.g. <module>/client
 		lines := strings.Split(fileContent, "\n")
 		for i, line := range lines {
 			if strings.HasPrefix(line, "module ") {
-				lines[i] = "module " + projectImportPath
+				lines[i] = "module " + path.Join(projectImportPath, path.Dir(targetPath))
 				break
 			}
 		}
 		fileContent = strings.Join(lines, "\n")
 	}
 
-	// Create directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-		return err
-	}
-
-	// Write processed content
-	return os.WriteFile(targetPath, []byte(fileContent), 0644)
+	return []byte(fileContent), nil
 }
 
-func initGoModule(projectName, modulePath string) {
+// initGoModule creates go.mod in projectDir and adds the bappa modules to it
+func initGoModule(projectDir, modulePath string, modules []string, version string) error {
 	fmt.Println("\nSetting up Go module...")
 
-	// Change to project directory
-	currentDir, _ := os.Getwd()
-	projectDir := filepath.Join(currentDir, projectName)
-	err := os.Chdir(projectDir)
-	if err != nil {
-		fmt.Printf("Error changing to project directory: %v\n", err)
-		return
-	}
-	defer os.Chdir(currentDir)
-
 	// Initialize go.mod with the correct module name
-	runCommand("go", "mod", "init", modulePath)
+	if err := runCommand(projectDir, "go", "mod", "init", modulePath); err != nil {
+		return err
+	}
 
 	// Get required dependencies
-	runCommand("go", "get", "github.com/TheBitDrifter/bappa/coldbrew@latest")
-	runCommand("go", "get", "github.com/TheBitDrifter/bappa/blueprint@latest")
-	runCommand("go", "get", "github.com/TheBitDrifter/bappa/warehouse@latest")
-	runCommand("go", "get", "github.com/TheBitDrifter/bappa/tteokbokki@latest")
-	runCommand("go", "get", "github.com/TheBitDrifter/bappa/table@latest")
+	for _, module := range modules {
+		if err := runCommand(projectDir, "go", "get", module+"@"+version); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func runCommand(command string, args ...string) {
+// runCommand runs command in dir, streaming its output
+func runCommand(dir, command string, args ...string) error {
 	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	fmt.Printf("Running: %s %s\n", command, strings.Join(args, " "))
 
 	if err := cmd.Run(); err != nil {
-		fmt.Printf("Warning: Command failed: %v\n", err)
+		return fmt.Errorf("%s %s failed: %v", command, strings.Join(args, " "), err)
 	}
+	return nil
 }
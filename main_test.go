@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckProjectDir(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	used := filepath.Join(dir, "used")
+	file := filepath.Join(dir, "file")
+	for _, d := range []string{empty, used} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{filepath.Join(used, "main.go"), file} {
+		if err := os.WriteFile(name, []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		dir  string
+		mode writeMode
+		err  string
+	}{
+		{dir: filepath.Join(dir, "missing"), mode: writeNew},
+		{dir: empty, mode: writeNew},
+		{dir: used, mode: writeNew, err: "already exists and is not empty (use --force to overwrite it or --merge to only add missing files)"},
+		{dir: used, mode: writeForce},
+		{dir: used, mode: writeMerge},
+		{dir: file, mode: writeNew, err: "checking project directory"},
+		{dir: file, mode: writeForce, err: "checking project directory"},
+	}
+	for _, tt := range tests {
+		err := checkProjectDir(tt.dir, tt.mode)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("checkProjectDir(%s, %d): %v", filepath.Base(tt.dir), tt.mode, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("checkProjectDir(%s, %d) = %v, want an error containing %q", filepath.Base(tt.dir), tt.mode, err, tt.err)
+		}
+	}
+}
+
+// TestCreateProjectExisting generates into a project the user changed, which
+// only --force and --merge do, and each in its own way
+func TestCreateProjectExisting(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	opts := projectOptions{Name: "me/game", Template: "topdown", NoHooks: true, Deps: dependencyOptions{Offline: true}}
+	if err := createProject(opts); err != nil {
+		t.Fatal(err)
+	}
+	template, err := os.ReadFile(filepath.Join("game", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited := []byte("package main\n\n// Edited by the user\nfunc main() {}\n")
+	if err := os.WriteFile(filepath.Join("game", "main.go"), edited, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join("game", "scenes", "helpers.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("game", "notes.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check := func(mode, name string, want []byte) {
+		t.Helper()
+		if got, _ := os.ReadFile(filepath.Join("game", name)); string(got) != string(want) {
+			t.Errorf("after %s, %s is\n%s\nwant\n%s", mode, name, got, want)
+		}
+	}
+
+	if err := createProject(opts); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("generating into the project again = %v, want it refused", err)
+	}
+	check("a refused generation", "main.go", edited)
+
+	// --merge only adds what is missing
+	opts.Merge = true
+	if err := createProject(opts); err != nil {
+		t.Fatal(err)
+	}
+	check("--merge", "main.go", edited)
+	check("--merge", "notes.txt", []byte("mine\n"))
+	if _, err := os.Stat(filepath.Join("game", "scenes", "helpers.go")); err != nil {
+		t.Errorf("--merge didn't add the missing scenes/helpers.go: %v", err)
+	}
+
+	// --force overwrites the template's files and keeps the others
+	opts.Merge, opts.Force = false, true
+	if err := createProject(opts); err != nil {
+		t.Fatal(err)
+	}
+	check("--force", "main.go", template)
+	check("--force", "notes.txt", []byte("mine\n"))
+}
+
+// TestCreateProjectModulePath generates projects with a --module path of more than
+// two elements and checks every import and module line uses it
+func TestCreateProjectModulePath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	const modulePath = "git.example.internal/games/space"
+
+	tests := []struct {
+		template string
+		dir      string
+		goMods   map[string]string // module lines by go.mod
+	}{
+		{template: "topdown", dir: "space", goMods: map[string]string{"go.mod": modulePath}},
+		{
+			template: "platformer-netcode",
+			dir:      "space-net",
+			goMods: map[string]string{
+				"client/go.mod": modulePath + "/client",
+				"server/go.mod": modulePath + "/server",
+				"shared/go.mod": modulePath + "/shared",
+			},
+		},
+	}
+	for _, tt := range tests {
+		opts := projectOptions{Name: tt.dir, Module: modulePath, Template: tt.template, NoHooks: true, Deps: dependencyOptions{Offline: true}}
+		if err := createProject(opts); err != nil {
+			t.Fatalf("%s: %v", tt.template, err)
+		}
+
+		projectImports := 0
+		for name, content := range readTree(t, tt.dir) {
+			if strings.Contains(content, "bappacreate/templates") {
+				t.Errorf("%s: %s still mentions the template path", tt.template, name)
+			}
+			if strings.HasSuffix(name, ".go") {
+				projectImports += strings.Count(content, `"`+modulePath+"/")
+			}
+		}
+		if projectImports == 0 {
+			t.Errorf("%s: no Go file imports a package of %s", tt.template, modulePath)
+		}
+		for goMod, module := range tt.goMods {
+			content, err := os.ReadFile(filepath.Join(tt.dir, goMod))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(content), "module "+module+"\n") {
+				t.Errorf("%s: %s doesn't declare module %s:\n%s", tt.template, goMod, module, content)
+			}
+		}
+	}
+}
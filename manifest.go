@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// Every directory under templates/ that carries this file is a selectable template
+const manifestFileName = "template.json"
+
+// Template used when `new` is run without --template
+const defaultTemplate = "topdown"
+
+// Root of the bappa engine modules listed in template manifests
+const bappaModuleRoot = "github.com/TheBitDrifter/bappa"
+
+// TemplateManifest describes a template and what a generated project needs
+type TemplateManifest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Tags        []string        `json:"tags"`
+	Modules     []string        `json:"modules"` // bappa modules, e.g. "coldbrew" for github.com/TheBitDrifter/bappa/coldbrew
+	Resolution  Resolution      `json:"resolution"`
+	ImportPath  string          `json:"importPath,omitempty"` // import path the template's own packages use, see SourceImportPath
+	Common      []CommonPackage `json:"common,omitempty"`
+	Genre       string          `json:"genre,omitempty"` // base template of a preset, see resolveTemplate
+	With        []string        `json:"with,omitempty"`  // feature layers a preset adds to its genre
+	Hooks       []Hook          `json:"hooks,omitempty"` // steps run in the project once it's generated
+}
+
+// Directory holding the packages templates can inherit with "common"
+const commonDir = "templates/common"
+
+// CommonPackage is a package under templates/common that a template inherits.
+// Its files are copied into the project and imports of it point at the copy.
+type CommonPackage struct {
+	Package string   `json:"package"`           // directory under templates/common, e.g. "coresystems"
+	Dest    string   `json:"dest,omitempty"`    // directory in the project, defaults to Package
+	Exclude []string `json:"exclude,omitempty"` // files of the package the template doesn't use
+}
+
+// commonFile is a file a template inherits from templates/common
+type commonFile struct {
+	Source string // path in templateFS
+	Dest   string // path in the project
+}
+
+// destination returns the project directory the package is copied to
+func (c CommonPackage) destination() string {
+	if c.Dest != "" {
+		return c.Dest
+	}
+	return c.Package
+}
+
+// files lists the files of the package that are copied into the project
+func (c CommonPackage) files() ([]commonFile, error) {
+	dir := path.Join(commonDir, c.Package)
+	entries, err := fs.ReadDir(templateFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("common package %s: %v", c.Package, err)
+	}
+
+	excluded := map[string]bool{}
+	for _, name := range c.Exclude {
+		excluded[name] = true
+	}
+
+	var files []commonFile
+	for _, entry := range entries {
+		if entry.IsDir() || excluded[entry.Name()] || skipTemplateFile(entry.Name()) {
+			continue
+		}
+		files = append(files, commonFile{
+			Source: path.Join(dir, entry.Name()),
+			Dest:   path.Join(c.destination(), entry.Name()),
+		})
+	}
+	return files, nil
+}
+
+// commonFiles lists every file the template inherits from templates/common
+func (m *TemplateManifest) commonFiles() ([]commonFile, error) {
+	var files []commonFile
+	for _, pkg := range m.Common {
+		pkgFiles, err := pkg.files()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, pkgFiles...)
+	}
+	return files, nil
+}
+
+// commonImportMappings maps the import path of every inherited common package to
+// the path of its copy in the template
+func (m *TemplateManifest) commonImportMappings() map[string]string {
+	mappings := map[string]string{}
+	for _, pkg := range m.Common {
+		mappings[commonImportPattern+"/"+pkg.Package] = builtinImportPath(m.Name) + "/" + pkg.destination()
+	}
+	return mappings
+}
+
+// Resolution is a window size in pixels
+type Resolution struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func (r Resolution) String() string {
+	return fmt.Sprintf("%dx%d", r.Width, r.Height)
+}
+
+// parseResolution parses a resolution written as <width>x<height>, e.g. 640x360
+func parseResolution(s string) (Resolution, error) {
+	width, height, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	r := Resolution{}
+	if ok {
+		r.Width, _ = strconv.Atoi(width)
+		r.Height, _ = strconv.Atoi(height)
+	}
+	if r.Width <= 0 || r.Height <= 0 {
+		return Resolution{}, fmt.Errorf("invalid resolution %q, expected <width>x<height> like 640x360", s)
+	}
+	return r, nil
+}
+
+// HasTag reports whether the manifest is tagged with tag
+func (m *TemplateManifest) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SourceImportPath returns the import path the template's Go files use for their
+// own packages. Templates outside this repository set it with importPath.
+func (m *TemplateManifest) SourceImportPath() string {
+	if m.ImportPath != "" {
+		return m.ImportPath
+	}
+	return builtinImportPath(m.Name)
+}
+
+// builtinImportPath returns the import path of the template name in this repository
+func builtinImportPath(name string) string {
+	return "github.com/TheBitDrifter/bappacreate/templates/" + name
+}
+
+// ModulePaths returns the full import paths of the required bappa modules
+func (m *TemplateManifest) ModulePaths() []string {
+	paths := make([]string, len(m.Modules))
+	for i, module := range m.Modules {
+		paths[i] = bappaModuleRoot + "/" + module
+	}
+	return paths
+}
+
+func (m *TemplateManifest) validate(dir string) error {
+	if m.Name == "" {
+		return fmt.Errorf("%s: missing name", dir)
+	}
+	if m.Description == "" {
+		return fmt.Errorf("%s: missing description", dir)
+	}
+	if len(m.Modules) == 0 {
+		return fmt.Errorf("%s: no bappa modules listed", dir)
+	}
+	if m.Resolution.Width <= 0 || m.Resolution.Height <= 0 {
+		return fmt.Errorf("%s: invalid resolution %s", dir, m.Resolution)
+	}
+	if m.ImportPath != "" {
+		if err := module.CheckImportPath(m.ImportPath); err != nil {
+			return fmt.Errorf("%s: invalid importPath: %v", dir, err)
+		}
+	}
+	if m.Genre != "" {
+		if len(m.With) == 0 {
+			return fmt.Errorf("%s: preset of %s lists no features", dir, m.Genre)
+		}
+		if len(m.Common) > 0 {
+			return fmt.Errorf("%s: presets inherit common packages from their genre and features", dir)
+		}
+	} else if len(m.With) > 0 {
+		return fmt.Errorf("%s: features need a genre", dir)
+	}
+	if err := validateHooks(dir, m.Hooks); err != nil {
+		return err
+	}
+	return validateCommon(dir, m.Common)
+}
+
+// validateCommon checks that every common package and excluded file exists
+func validateCommon(dir string, common []CommonPackage) error {
+	for _, pkg := range common {
+		if pkg.Package == "" || !fs.ValidPath(pkg.Package) || pkg.Package == "." {
+			return fmt.Errorf("%s: invalid common package %q", dir, pkg.Package)
+		}
+		if dest := pkg.destination(); !fs.ValidPath(dest) || dest == "." {
+			return fmt.Errorf("%s: invalid destination %q for common package %s", dir, dest, pkg.Package)
+		}
+
+		pkgDir := path.Join(commonDir, pkg.Package)
+		if info, err := fs.Stat(templateFS, pkgDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("%s: common package %s not found in %s", dir, pkg.Package, commonDir)
+		}
+		for _, name := range pkg.Exclude {
+			if _, err := fs.Stat(templateFS, path.Join(pkgDir, name)); err != nil {
+				return fmt.Errorf("%s: excluded common file %s not found", dir, path.Join(pkgDir, name))
+			}
+		}
+	}
+	return nil
+}
+
+// manifestError is a manifest that was read but can't be used, as opposed to one
+// that couldn't be read
+type manifestError struct {
+	err error
+}
+
+func (e *manifestError) Error() string { return e.err.Error() }
+func (e *manifestError) Unwrap() error { return e.err }
+
+// readManifest reads and validates the manifest of the template in dir
+func readManifest(fsys fs.FS, dir string) (*TemplateManifest, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, manifestFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &TemplateManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, &manifestError{fmt.Errorf("%s: invalid manifest: %v", dir, err)}
+	}
+	if err := manifest.validate(dir); err != nil {
+		return nil, &manifestError{err}
+	}
+	return manifest, nil
+}
+
+// loadManifests reads the manifest of every template in fsys under root, sorted by name
+func loadManifests(fsys fs.FS, root string) ([]*TemplateManifest, error) {
+	entries, err := fs.ReadDir(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []*TemplateManifest
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := path.Join(root, entry.Name())
+		if _, err := fs.Stat(fsys, path.Join(dir, manifestFileName)); err != nil {
+			// Directories without a manifest, like common, only hold shared files
+			continue
+		}
+
+		manifest, err := readManifest(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		if manifest.Name != entry.Name() {
+			return nil, &manifestError{fmt.Errorf("%s: name %q does not match its directory", dir, manifest.Name)}
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].Name < manifests[j].Name
+	})
+	return manifests, nil
+}
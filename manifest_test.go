@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// A manifest readManifest accepts, for the cases to break
+const validManifest = `{"name": "game", "description": "a game", "modules": ["coldbrew"], "resolution": {"width": 640, "height": 360}`
+
+func TestReadManifestErrors(t *testing.T) {
+	tests := []struct {
+		manifest string
+		err      string
+	}{
+		{manifest: `{"name": `, err: "invalid manifest"},
+		{manifest: `{"description": "a game", "modules": ["coldbrew"], "resolution": {"width": 640, "height": 360}}`, err: "missing name"},
+		{manifest: `{"name": "game", "modules": ["coldbrew"], "resolution": {"width": 640, "height": 360}}`, err: "missing description"},
+		{manifest: `{"name": "game", "description": "a game", "resolution": {"width": 640, "height": 360}}`, err: "no bappa modules listed"},
+		{manifest: `{"name": "game", "description": "a game", "modules": ["coldbrew"], "resolution": {"width": 640}}`, err: "invalid resolution 640x0"},
+		{manifest: validManifest + `, "importPath": "not a path"}`, err: "invalid importPath"},
+		{manifest: validManifest + `, "genre": "topdown"}`, err: "preset of topdown lists no features"},
+		{manifest: validManifest + `, "genre": "topdown", "with": ["split"], "common": [{"package": "sounds"}]}`, err: "presets inherit common packages"},
+		{manifest: validManifest + `, "with": ["split"]}`, err: "features need a genre"},
+		{manifest: validManifest + `, "common": [{"package": "missing"}]}`, err: "common package missing not found"},
+		{manifest: validManifest + `, "common": [{"package": "sounds", "exclude": ["missing.go"]}]}`, err: "excluded common file templates/common/sounds/missing.go not found"},
+		{manifest: validManifest + `, "common": [{"package": "sounds", "dest": "../sounds"}]}`, err: `invalid destination "../sounds"`},
+		{manifest: validManifest + `, "common": [{"package": ""}]}`, err: `invalid common package ""`},
+		{manifest: validManifest + `, "common": [{"package": "../templates/topdown"}]}`, err: `invalid common package "../templates/topdown"`},
+		{manifest: validManifest + `, "common": [{"package": "sounds", "dest": "."}]}`, err: `invalid destination "."`},
+		{manifest: validManifest + `, "hooks": ["git-int"]}`, err: `unknown hook "git-int"`},
+	}
+	for _, tt := range tests {
+		fsys := fstest.MapFS{"game/template.json": {Data: []byte(tt.manifest)}}
+		_, err := readManifest(fsys, "game")
+		var manifestErr *manifestError
+		if err == nil || !strings.Contains(err.Error(), tt.err) || !errors.As(err, &manifestErr) {
+			t.Errorf("readManifest(%s) = %v, want a manifest error containing %q", tt.manifest, err, tt.err)
+		}
+	}
+
+	if _, err := readManifest(fstest.MapFS{}, "game"); err == nil || errors.As(err, new(*manifestError)) {
+		t.Errorf("readManifest without template.json = %v, want a read error", err)
+	}
+}
+
+func TestLoadManifests(t *testing.T) {
+	manifest := func(name string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(strings.Replace(validManifest, `"game"`, `"`+name+`"`, 1) + "}")}
+	}
+	fsys := fstest.MapFS{
+		"templates/zombies/template.json": manifest("zombies"),
+		"templates/arena/template.json":   manifest("arena"),
+		"templates/common/tags.go":        {Data: []byte("package common\n")},
+	}
+	manifests, err := loadManifests(fsys, "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range manifests {
+		names = append(names, m.Name)
+	}
+	if strings.Join(names, ",") != "arena,zombies" {
+		t.Errorf("loadManifests = %q, want the templates sorted by name without common", names)
+	}
+
+	fsys["templates/renamed/template.json"] = manifest("arena")
+	if _, err := loadManifests(fsys, "templates"); err == nil || !strings.Contains(err.Error(), `name "arena" does not match its directory`) {
+		t.Errorf("loadManifests with a misnamed template = %v", err)
+	}
+}
+
+// TestBuiltinManifests checks that every embedded template has a valid manifest
+func TestBuiltinManifests(t *testing.T) {
+	manifests, err := loadManifests(templateFS, "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, m := range manifests {
+		found[m.Name] = true
+	}
+	for _, name := range []string{defaultTemplate, "platformer", "platformer-netcode", "sandbox"} {
+		if !found[name] {
+			t.Errorf("no manifest for the built-in template %s", name)
+		}
+	}
+}
+
+func TestFindTemplate(t *testing.T) {
+	templates := func(names ...string) []availableTemplate {
+		var available []availableTemplate
+		for _, name := range names {
+			available = append(available, availableTemplate{Manifest: &TemplateManifest{Name: name}})
+		}
+		return available
+	}
+	tests := []struct {
+		name      string
+		templates []availableTemplate
+		want      string
+		err       string
+	}{
+		{name: "platformer", templates: templates("platformer", "topdown"), want: "platformer"},
+		{name: "", templates: templates("platformer", "topdown"), want: defaultTemplate},
+		{name: "", templates: templates("studio"), want: "studio"},
+		{name: "", templates: templates("arena", "studio"), err: "template 'topdown' not found (available: arena, studio)"},
+		{name: "platformr", templates: templates("platformer", "topdown"), err: "template 'platformr' not found (available: platformer, topdown)"},
+	}
+	for _, tt := range tests {
+		got, err := findTemplate(tt.name, tt.templates)
+		switch {
+		case tt.err != "":
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("findTemplate(%q) = %v, want error %q", tt.name, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("findTemplate(%q): %v", tt.name, err)
+		case got.Manifest.Name != tt.want:
+			t.Errorf("findTemplate(%q) = %s, want %s", tt.name, got.Manifest.Name, tt.want)
+		}
+	}
+}
+
+func TestCommonPackageFiles(t *testing.T) {
+	tests := []struct {
+		pkg  CommonPackage
+		want []commonFile
+	}{
+		{
+			pkg:  CommonPackage{Package: "components"},
+			want: []commonFile{{Source: "templates/common/components/components.go", Dest: "components/components.go"}, {Source: "templates/common/components/tags.go", Dest: "components/tags.go"}},
+		},
+		{
+			pkg:  CommonPackage{Package: "components", Dest: "game/components", Exclude: []string{"tags.go"}},
+			want: []commonFile{{Source: "templates/common/components/components.go", Dest: "game/components/components.go"}},
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.pkg.files()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%+v files = %v, want %v", tt.pkg, got, tt.want)
+		}
+	}
+
+	if _, err := (CommonPackage{Package: "missing"}).files(); err == nil || !strings.Contains(err.Error(), "common package missing") {
+		t.Errorf("files of a missing package = %v", err)
+	}
+}
+
+func TestCommonImportMappings(t *testing.T) {
+	manifest := testManifest()
+	manifest.Common = []CommonPackage{{Package: "coresystems", Dest: "systems/core"}, {Package: "sounds"}}
+	want := map[string]string{
+		"github.com/TheBitDrifter/bappacreate/templates/common/coresystems": "github.com/TheBitDrifter/bappacreate/templates/game/systems/core",
+		"github.com/TheBitDrifter/bappacreate/templates/common/sounds":      "github.com/TheBitDrifter/bappacreate/templates/game/sounds",
+	}
+	if got := manifest.commonImportMappings(); !maps.Equal(got, want) {
+		t.Errorf("commonImportMappings = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/text/unicode/norm"
+)
+
+// projectNames are the names derived from the `new` arguments
+type projectNames struct {
+	Dir        string // directory the project is generated into
+	ModulePath string // Go module path all template imports are rewritten to
+	Identifier string // Go identifier safe form of the project name, e.g. for package names
+	Title      string // human readable form of the project name, e.g. for window titles
+	Author     string // owner of the module path on a code host, may be empty
+}
+
+// Code hosts whose module paths start with host/owner
+var codeHosts = map[string]bool{
+	"github.com":    true,
+	"gitlab.com":    true,
+	"bitbucket.org": true,
+	"codeberg.org":  true,
+}
+
+// resolveProjectNames works out and validates the project directory and module path.
+//
+// Without a module path, name must be username/project-name and the module is
+// github.com/username/project-name. A name whose first element looks like a host
+// (it contains a dot) is used as the module path as-is. An explicit module path
+// may have any number of elements; name then only picks the directory and
+// defaults to the last element of the module path.
+func resolveProjectNames(name, modulePath string) (projectNames, error) {
+	modulePath = strings.Trim(modulePath, "/")
+
+	var names projectNames
+	switch {
+	case modulePath != "":
+		names = projectNames{Dir: name, ModulePath: modulePath}
+		if names.Dir == "" {
+			names.Dir = path.Base(modulePath)
+		}
+
+	case strings.Contains(strings.Split(name, "/")[0], "."):
+		name = strings.Trim(name, "/")
+		names = projectNames{Dir: path.Base(name), ModulePath: name}
+
+	default:
+		// Check if the project name contains a username
+		parts := strings.Split(strings.Trim(name, "/"), "/")
+		if len(parts) != 2 {
+			return projectNames{}, fmt.Errorf("project name must be in the format 'username/project-name', use --module for other module paths")
+		}
+
+		username := parts[0]
+		projectNameOnly := parts[1]
+		names = projectNames{
+			Dir:        projectNameOnly,
+			ModulePath: fmt.Sprintf("github.com/%s/%s", username, projectNameOnly),
+		}
+	}
+
+	// The directory is checked however it was derived, also from --module or a host path
+	if err := checkProjectName(filepath.Base(names.Dir)); err != nil {
+		if modulePath != "" && name == "" {
+			return projectNames{}, fmt.Errorf("%v, or name the project directory after --module", err)
+		}
+		return projectNames{}, err
+	}
+	if err := module.CheckPath(names.ModulePath); err != nil {
+		return projectNames{}, fmt.Errorf("invalid module path: %v", err)
+	}
+
+	names.Identifier = projectIdentifier(path.Base(names.ModulePath))
+	names.Title = projectTitle(path.Base(names.ModulePath))
+	names.Author = moduleOwner(names.ModulePath)
+	return names, nil
+}
+
+// moduleOwner returns the user or organization of a module path on a known
+// code host, e.g. "johndoe" for github.com/johndoe/my-game
+func moduleOwner(modulePath string) string {
+	parts := strings.Split(modulePath, "/")
+	if len(parts) < 3 || !codeHosts[parts[0]] {
+		return ""
+	}
+	return parts[1]
+}
+
+// checkProjectName rejects project names that aren't a clean, lowercase module path element
+func checkProjectName(name string) error {
+	suggestion := suggestProjectName(name)
+	if name == suggestion {
+		return nil
+	}
+	if suggestion == "" {
+		return fmt.Errorf("invalid project name %q: use lowercase letters, digits, '-', '_' and '.'", name)
+	}
+	return fmt.Errorf("invalid project name %q: use lowercase letters, digits, '-', '_' and '.' (try %q)", name, suggestion)
+}
+
+// suggestProjectName turns name into a lowercase module path element,
+// e.g. "My Awesome Game!" becomes "my-awesome-game"
+func suggestProjectName(name string) string {
+	// Split accented letters so "über" keeps its "u"
+	var b strings.Builder
+	lastDash := false
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Drop the combining accent
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '.':
+			b.WriteRune(r)
+			lastDash = false
+		case !lastDash:
+			b.WriteByte('-')
+			lastDash = true
+		}
+	}
+
+	// Leading dots would make a hidden directory, ".." would escape it
+	suggestion := strings.Trim(b.String(), "-.")
+	for strings.Contains(suggestion, "..") {
+		suggestion = strings.ReplaceAll(suggestion, "..", ".")
+	}
+	return suggestion
+}
+
+// projectIdentifier returns a valid Go identifier for name, e.g. "my-game" becomes "mygame"
+func projectIdentifier(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+
+	identifier := b.String()
+	switch {
+	case identifier == "":
+		return "game"
+	case identifier[0] >= '0' && identifier[0] <= '9':
+		return "game" + identifier
+	case token.IsKeyword(identifier):
+		return identifier + "game"
+	}
+	return identifier
+}
+
+// projectTitle returns a title for name, e.g. "my-awesome-game" becomes "My Awesome Game"
+func projectTitle(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.' || r == ' '
+	})
+	for i, word := range words {
+		first, size := utf8.DecodeRuneInString(word)
+		words[i] = string(unicode.ToUpper(first)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// exportedName returns name as an exported Go identifier, e.g. "dash" becomes
+// "Dash". what names the kind of thing in errors.
+func exportedName(what, name string) (string, error) {
+	if !token.IsIdentifier(name) {
+		return "", fmt.Errorf("invalid %s name %q: use a Go identifier, e.g. Dash", what, name)
+	}
+	first, size := utf8.DecodeRuneInString(name)
+	if !unicode.IsLetter(first) {
+		return "", fmt.Errorf("invalid %s name %q: it must start with a letter", what, name)
+	}
+	return string(unicode.ToUpper(first)) + name[size:], nil
+}
+
+// unexportedName returns the unexported form of a Go identifier, e.g. "EnemyTag"
+// becomes "enemyTag" and "HUDTag" becomes "hudTag"
+func unexportedName(name string) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsUpper(r) || i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(r)
+	}
+	return string(runes)
+}
+
+// snakeCase returns the file name form of a Go identifier, e.g. "HUDRenderSystem"
+// becomes "hud_render_system"
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveProjectNames(t *testing.T) {
+	tests := []struct {
+		name, module string
+		want         projectNames
+	}{
+		{
+			name: "johndoe/my-game",
+			want: projectNames{Dir: "my-game", ModulePath: "github.com/johndoe/my-game", Identifier: "mygame", Title: "My Game", Author: "johndoe"},
+		},
+		{
+			name: "/johndoe/space_shooter/",
+			want: projectNames{Dir: "space_shooter", ModulePath: "github.com/johndoe/space_shooter", Identifier: "spaceshooter", Title: "Space Shooter", Author: "johndoe"},
+		},
+		{
+			name: "gitlab.com/studio/games/arena",
+			want: projectNames{Dir: "arena", ModulePath: "gitlab.com/studio/games/arena", Identifier: "arena", Title: "Arena", Author: "studio"},
+		},
+		{
+			module: "git.example.com/games/space",
+			want:   projectNames{Dir: "space", ModulePath: "git.example.com/games/space", Identifier: "space", Title: "Space"},
+		},
+		{
+			name: "../space-game", module: "git.example.com/games/Space",
+			want: projectNames{Dir: "../space-game", ModulePath: "git.example.com/games/Space", Identifier: "space", Title: "Space"},
+		},
+		{
+			name: "me/2048",
+			want: projectNames{Dir: "2048", ModulePath: "github.com/me/2048", Identifier: "game2048", Title: "2048", Author: "me"},
+		},
+		{
+			name: "me/func",
+			want: projectNames{Dir: "func", ModulePath: "github.com/me/func", Identifier: "funcgame", Title: "Func", Author: "me"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := resolveProjectNames(tt.name, tt.module)
+		if err != nil {
+			t.Errorf("resolveProjectNames(%q, %q): %v", tt.name, tt.module, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveProjectNames(%q, %q) = %+v, want %+v", tt.name, tt.module, got, tt.want)
+		}
+	}
+}
+
+func TestResolveProjectNamesErrors(t *testing.T) {
+	tests := []struct {
+		name, module string
+		err          string // part of the error
+	}{
+		{name: "my-game", err: "format 'username/project-name'"},
+		{name: "a/b/c", err: "format 'username/project-name'"},
+		{name: "me/My Game", err: `(try "my-game")`},
+		{name: "me/a..b", err: `(try "a.b")`},
+		{name: "me/über", err: `(try "uber")`},
+		{name: "me/..", err: `invalid project name ".."`},
+		{name: "github.com/x/My..Game", err: `(try "my.game")`},
+		{module: "example.com/Game", err: `(try "game"), or name the project directory after --module`},
+		{name: "Space Game", module: "example.com/space", err: `(try "space-game")`},
+		{name: "space", module: "example.com/a b", err: "invalid module path"},
+		{name: "we!rd/game", err: "invalid module path"},
+	}
+	for _, tt := range tests {
+		_, err := resolveProjectNames(tt.name, tt.module)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("resolveProjectNames(%q, %q) = %v, want an error containing %q", tt.name, tt.module, err, tt.err)
+		}
+	}
+}
+
+func TestSuggestProjectName(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"my-game", "my-game"},
+		{"My Awesome Game!", "my-awesome-game"},
+		{"  spaces  ", "spaces"},
+		{"Crème Brûlée", "creme-brulee"},
+		{"..hidden", "hidden"},
+		{"a..b...c", "a.b.c"},
+		{"snake_case.v2", "snake_case.v2"},
+		{"游戏", ""},
+		{"---", ""},
+	}
+	for _, tt := range tests {
+		if got := suggestProjectName(tt.name); got != tt.want {
+			t.Errorf("suggestProjectName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Dash", "dash"},
+		{"PlayerMovementSystem", "player_movement_system"},
+		{"HUDRenderSystem", "hud_render_system"},
+		{"Level2Boss", "level2_boss"},
+		{"ID", "id"},
+		{"already_snake", "already_snake"},
+	}
+	for _, tt := range tests {
+		if got := snakeCase(tt.name); got != tt.want {
+			t.Errorf("snakeCase(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnexportedName(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{name: "EnemyTag", want: "enemyTag"},
+		{name: "HUDTag", want: "hudTag"},
+		{name: "ATag", want: "aTag"},
+		{name: "X", want: "x"},
+		{name: "already", want: "already"},
+	}
+	for _, tt := range tests {
+		if got := unexportedName(tt.name); got != tt.want {
+			t.Errorf("unexportedName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
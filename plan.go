@@ -0,0 +1,421 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// projectPlan lists everything project generation will do, in order
+type projectPlan struct {
+	Template    string        `json:"template"`
+	ProjectDir  string        `json:"projectDir"`
+	ModulePath  string        `json:"modulePath"`
+	Identifier  string        `json:"identifier"`
+	Title       string        `json:"title"`
+	Author      string        `json:"author,omitempty"`
+	Resolution  Resolution    `json:"resolution"`
+	Features    []string      `json:"features"`
+	Directories []string      `json:"directories"`
+	Files       []plannedFile `json:"files"`
+	Skipped     []skippedFile `json:"skipped"`
+	Modules     []string      `json:"modules"`
+	Offline     bool          `json:"offline,omitempty"`
+	GoModules   []string      `json:"goModules,omitempty"` // directories of the template's own go.mod files
+	Commands    []string      `json:"commands"`
+	Hooks       []Hook        `json:"hooks"`
+
+	// Shell commands of a --template-dir or --template-repo template, not run
+	// without --allow-hooks
+	BlockedHooks []Hook `json:"blockedHooks,omitempty"`
+
+	manifest *TemplateManifest
+}
+
+// plannedFile is a file written into the project. Paths are slash separated
+// and relative to the project directory.
+type plannedFile struct {
+	Path       string          `json:"path"`
+	Source     string          `json:"source"`
+	CommonFile string          `json:"commonFile,omitempty"` // file under templates/common the file is inherited from
+	Binary     bool            `json:"binary,omitempty"`
+	Rendered   bool            `json:"rendered,omitempty"`  // source is a .tmpl file rendered with text/template
+	Generated  string          `json:"generated,omitempty"` // why a file without a template source is created
+	Rewrites   []importRewrite `json:"rewrites,omitempty"`
+
+	content []byte // content of generated files
+	fsys    fs.FS  // where the source is read from
+	name    string // path of the source in fsys
+}
+
+// importRewrite is an import path that gets replaced in a file
+type importRewrite struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// skippedFile is a template file that is not copied because a common file or a
+// feature layer replaces it, or a feature leaves it out
+type skippedFile struct {
+	Path         string `json:"path"`
+	Source       string `json:"source"`
+	OverriddenBy string `json:"overriddenBy,omitempty"`
+	RemovedBy    string `json:"removedBy,omitempty"` // feature leaving the file out
+}
+
+// Matches any template path mentioned in a non-Go file
+var templatePathPattern = regexp.MustCompile(`github\.com/TheBitDrifter/bappacreate/templates/[\w./-]+`)
+
+// buildPlan works out which files a project generated from manifest will contain.
+// Every file that can't be planned is reported, not just the first one.
+func buildPlan(manifest *TemplateManifest, source templateSource, names projectNames, deps dependencyOptions) (*projectPlan, error) {
+	templateName := manifest.Name
+	modulePath := names.ModulePath
+
+	files, err := source.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &projectPlan{
+		Template:   templateName,
+		ProjectDir: names.Dir,
+		ModulePath: modulePath,
+		Identifier: names.Identifier,
+		Title:      names.Title,
+		Author:     names.Author,
+		Resolution: manifest.Resolution,
+		Features:   manifest.Tags,
+		Modules:    manifest.ModulePaths(),
+		Offline:    deps.Offline,
+		manifest:   manifest,
+	}
+
+	inherited, err := manifest.commonFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// Destinations that will be created from a common template file
+	overrides := map[string]string{}
+	for _, common := range inherited {
+		overrides[common.Dest] = common.Source
+	}
+
+	// Feature layers are walked after their base template, replacing its files
+	layers := []templateLayer{{Source: source}}
+	removed := map[string]string{}
+	if layered, ok := source.(layeredTemplate); ok {
+		layers = append([]templateLayer{{Source: layered.base}}, layered.layers...)
+		removed = layered.remove
+	}
+
+	var errs []error
+	assetDirs := []string{}
+	planned := map[string]int{}        // index in plan.Files of every template file
+	plannedBy := map[string]int{}      // index in layers of the layer that planned it
+	conflicts := map[string][]string{} // files two features replace, by the features
+	for i, layer := range layers {
+		layerFiles, err := layer.Source.Files()
+		if err != nil {
+			return nil, err
+		}
+		err = fs.WalkDir(layerFiles, ".", func(relPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// Skip the root template directory itself
+			if relPath == "." {
+				return nil
+			}
+
+			if skipTemplateFile(d.Name()) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+
+			sourcePath := path.Join(layer.Source.Location(), relPath)
+			rendered := !d.IsDir() && isTemplateFile(relPath)
+			relPath = strings.TrimSuffix(relPath, templateFileSuffix)
+
+			if d.IsDir() {
+				plan.addDirectory(relPath)
+				if d.Name() == "assets" && !slices.Contains(assetDirs, relPath) {
+					assetDirs = append(assetDirs, relPath)
+				}
+				return nil
+			}
+
+			// The manifest describes the template, it is not part of the project
+			if relPath == manifestFileName {
+				return nil
+			}
+
+			// Nested modules keep their own go.mod, the project doesn't get a new one
+			if path.Base(relPath) == "go.mod" {
+				plan.GoModules = append(plan.GoModules, path.Dir(relPath))
+			}
+
+			if commonSource, overridden := overrides[relPath]; overridden {
+				plan.Skipped = append(plan.Skipped, skippedFile{Path: relPath, Source: sourcePath, OverriddenBy: commonSource})
+				return nil
+			}
+			if feature, ok := removed[relPath]; ok && i == 0 {
+				plan.Skipped = append(plan.Skipped, skippedFile{Path: relPath, Source: sourcePath, RemovedBy: feature})
+				return nil
+			}
+
+			file := plannedFile{Path: relPath, Source: sourcePath, Rendered: rendered, fsys: layerFiles, name: relPath}
+			if rendered {
+				file.name += templateFileSuffix
+			}
+			if binaryExtensions[strings.ToLower(path.Ext(relPath))] && !rendered {
+				file.Binary = true
+			} else if file.Rewrites, err = planRewrites(plan, file); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", sourcePath, err))
+			}
+
+			index, exists := planned[relPath]
+			if !exists {
+				planned[relPath], plannedBy[relPath] = len(plan.Files), i
+				plan.Files = append(plan.Files, file)
+				return nil
+			}
+
+			// A layer replaces the file of the base template or of an earlier layer
+			previous := layers[plannedBy[relPath]]
+			switch {
+			case layer.Combined:
+				delete(conflicts, relPath)
+			case plannedBy[relPath] > 0 && !previous.Combined:
+				conflicts[relPath] = []string{previous.Feature, layer.Feature}
+			}
+			plan.Skipped = append(plan.Skipped, skippedFile{Path: relPath, Source: plan.Files[index].Source, OverriddenBy: sourcePath})
+			plan.Files[index], plannedBy[relPath] = file, i
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %v", layer.Source.Location(), err)
+		}
+	}
+
+	// Files two features change need a <genre>+<feature> layer settling them
+	for _, relPath := range slices.Sorted(maps.Keys(conflicts)) {
+		features := conflicts[relPath]
+		errs = append(errs, fmt.Errorf("features %s and %s both change %s, add it to %s", features[0], features[1], relPath,
+			path.Join(layersDir, features[1], manifest.Genre+"+"+features[0])))
+	}
+
+	// Additional directories that might not be in the templates
+	if len(assetDirs) == 0 {
+		assetDirs = append(assetDirs, "assets")
+	}
+	for _, dir := range assetDirs {
+		plan.addDirectory(path.Join(dir, "images"))
+		plan.addDirectory(path.Join(dir, "sounds"))
+	}
+
+	// Additional directories for split-screen co-op templates
+	if manifest.HasTag("split") {
+		plan.addDirectory("players")
+		plan.addDirectory("splitscreen")
+	}
+
+	for _, common := range inherited {
+		file := plannedFile{Path: common.Dest, Source: common.Source, CommonFile: common.Source, fsys: templateFS, name: common.Source}
+		if file.Rewrites, err = planRewrites(plan, file); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", file.Source, err))
+		}
+		plan.Files = append(plan.Files, file)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// Templates with their own modules already pin their dependencies
+	if len(plan.GoModules) > 0 {
+		if deps.BappaVersion != "" {
+			return nil, fmt.Errorf("--bappa-version is not supported for the %s template, its modules pin their own versions", templateName)
+		}
+
+		// A workspace lets the nested modules use each other and be built together from the project root
+		if len(plan.GoModules) > 1 || plan.GoModules[0] != "." {
+			goWork, err := workspaceFile(files, plan.GoModules)
+			if err != nil {
+				return nil, err
+			}
+			plan.Files = append(plan.Files, plannedFile{Path: "go.work", Generated: "workspace using the template's modules", content: goWork})
+		}
+		return plan, nil
+	}
+
+	// Offline projects get a complete go.mod instead of running go commands
+	if deps.Offline {
+		goMod, err := offlineGoMod(modulePath, plan.Modules, deps)
+		if err != nil {
+			return nil, err
+		}
+		goSum, err := offlineGoSum(deps)
+		if err != nil {
+			return nil, err
+		}
+		plan.Files = append(plan.Files,
+			plannedFile{Path: "go.mod", Generated: "offline module with pinned bappa versions", content: goMod},
+			plannedFile{Path: "go.sum", Generated: "checksums of the pinned modules", content: goSum},
+		)
+		return plan, nil
+	}
+
+	plan.Commands = append(plan.Commands, "go mod init "+modulePath)
+	for _, module := range plan.Modules {
+		plan.Commands = append(plan.Commands, "go get "+module+"@"+deps.version())
+	}
+
+	return plan, nil
+}
+
+// addDirectory adds dir to the plan unless it is already listed
+func (p *projectPlan) addDirectory(dir string) {
+	for _, existing := range p.Directories {
+		if existing == dir {
+			return
+		}
+	}
+	p.Directories = append(p.Directories, dir)
+}
+
+// templateData returns the data .tmpl files of the plan are rendered with
+func (p *projectPlan) templateData() templateData {
+	return templateData{
+		ProjectName: path.Base(filepath.ToSlash(p.ProjectDir)),
+		ModulePath:  p.ModulePath,
+		Identifier:  p.Identifier,
+		Title:       p.Title,
+		Author:      p.Author,
+		Template:    p.Template,
+		Resolution:  p.Resolution,
+		Features:    p.Features,
+	}
+}
+
+// readSource returns the template content of file, rendered when it is a .tmpl file
+func readSource(plan *projectPlan, file plannedFile) ([]byte, error) {
+	content, err := fs.ReadFile(file.fsys, file.name)
+	if err != nil {
+		return nil, err
+	}
+	if !file.Rendered {
+		return content, nil
+	}
+	return renderTemplate(file.Source, content, plan.templateData())
+}
+
+// planRewrites reports how the template import paths in file will be rewritten
+func planRewrites(plan *projectPlan, file plannedFile) ([]importRewrite, error) {
+	content, err := readSource(plan, file)
+	if err != nil {
+		return nil, err
+	}
+	manifest, modulePath := plan.manifest, plan.ModulePath
+	if path.Ext(file.Path) == ".go" {
+		return goImportRewrites(file.Source, content, manifest, modulePath)
+	}
+
+	pattern := templatePathPattern
+	if manifest.ImportPath != "" {
+		pattern = regexp.MustCompile(regexp.QuoteMeta(manifest.ImportPath) + `[\w./-]*|` + templatePathPattern.String())
+	}
+
+	var rewrites []importRewrite
+	seen := map[string]bool{}
+	for _, match := range pattern.FindAllString(string(content), -1) {
+		if seen[match] {
+			continue
+		}
+		seen[match] = true
+		if rewritten := rewriteTemplateImports(match, manifest, modulePath); rewritten != match {
+			rewrites = append(rewrites, importRewrite{From: match, To: rewritten})
+		}
+	}
+	return rewrites, nil
+}
+
+// printPlan writes a human readable description of plan to w
+func printPlan(w io.Writer, plan *projectPlan) {
+	fmt.Fprintf(w, "Plan for %s (template: %s, module: %s)\n", plan.ProjectDir, plan.Template, plan.ModulePath)
+
+	fmt.Fprintf(w, "\nFiles to create (%d):\n", len(plan.Files))
+	for _, file := range plan.Files {
+		switch {
+		case file.CommonFile != "":
+			fmt.Fprintf(w, "  %s (common file %s)\n", file.Path, file.CommonFile)
+		case file.Generated != "":
+			fmt.Fprintf(w, "  %s (generated: %s)\n", file.Path, file.Generated)
+		case file.Rendered:
+			fmt.Fprintf(w, "  %s (rendered from %s)\n", file.Path, file.Source)
+		default:
+			fmt.Fprintf(w, "  %s\n", file.Path)
+		}
+		for _, rewrite := range file.Rewrites {
+			fmt.Fprintf(w, "      import %s => %s\n", rewrite.From, rewrite.To)
+		}
+	}
+
+	if len(plan.Skipped) > 0 {
+		fmt.Fprintf(w, "\nTemplate files skipped (%d):\n", len(plan.Skipped))
+		for _, skipped := range plan.Skipped {
+			switch {
+			case skipped.RemovedBy != "":
+				fmt.Fprintf(w, "  %s (left out by the %s feature)\n", skipped.Path, skipped.RemovedBy)
+			case strings.HasPrefix(skipped.OverriddenBy, commonDir+"/"):
+				fmt.Fprintf(w, "  %s (overridden by common file %s)\n", skipped.Path, skipped.OverriddenBy)
+			default:
+				fmt.Fprintf(w, "  %s (replaced by %s)\n", skipped.Path, skipped.OverriddenBy)
+			}
+		}
+	}
+
+	switch {
+	case len(plan.GoModules) > 0:
+		fmt.Fprintf(w, "\nNo commands to run, the template's modules pin their own dependencies: %s\n", strings.Join(plan.GoModules, ", "))
+	case len(plan.Commands) == 0:
+		fmt.Fprintln(w, "\nNo commands to run, the project is generated offline.")
+	default:
+		fmt.Fprintln(w, "\nCommands to run:")
+		for _, command := range plan.Commands {
+			fmt.Fprintf(w, "  %s\n", command)
+		}
+	}
+
+	if len(plan.Hooks) > 0 {
+		fmt.Fprintln(w, "\nHooks to run (skip them with --no-hooks):")
+		for _, hook := range plan.Hooks {
+			fmt.Fprintf(w, "  %s\n", hook)
+		}
+	}
+	if len(plan.BlockedHooks) > 0 {
+		fmt.Fprintln(w, "\nTemplate commands not run (run them with --allow-hooks):")
+		for _, hook := range plan.BlockedHooks {
+			fmt.Fprintf(w, "  %s\n", hook)
+		}
+	}
+}
+
+// printPlanJSON writes plan to w as indented JSON
+func printPlanJSON(w io.Writer, plan *projectPlan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
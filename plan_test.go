@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// mapTemplate is a template held in memory, for plans of made up templates
+type mapTemplate fstest.MapFS
+
+func (t mapTemplate) Files() (fs.FS, error) { return fstest.MapFS(t), nil }
+func (t mapTemplate) Location() string      { return "testdata/game" }
+
+// testManifest returns the manifest of a made up template called game
+func testManifest() *TemplateManifest {
+	return &TemplateManifest{
+		Name:        "game",
+		Description: "a game",
+		Modules:     []string{"coldbrew"},
+		Resolution:  Resolution{Width: 640, Height: 360},
+	}
+}
+
+// testPlan plans me/game from the made up template files
+func testPlan(t *testing.T, manifest *TemplateManifest, files map[string]string, deps dependencyOptions) (*projectPlan, error) {
+	t.Helper()
+	names, err := resolveProjectNames("me/game", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := mapTemplate{}
+	for name, content := range files {
+		template[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return buildPlan(manifest, template, names, deps)
+}
+
+// plannedPaths returns the paths of files
+func plannedPaths(files []plannedFile) []string {
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+func TestBuildPlan(t *testing.T) {
+	manifest := testManifest()
+	manifest.Common = []CommonPackage{{Package: "sounds"}}
+	files := map[string]string{
+		"template.json":            "{}",
+		"main.go":                  "package main\n\nimport _ \"github.com/TheBitDrifter/bappacreate/templates/game/scenes\"\n",
+		"scenes/scene.go":          "package scenes\n",
+		"sounds/sounds.go":         "package sounds\n",
+		"README.md":                "See github.com/TheBitDrifter/bappacreate/templates/game/scenes\n",
+		"assets/images/player.png": "\x89PNG github.com/TheBitDrifter/bappacreate/templates/game",
+		".hidden/secret.go":        "package hidden\n",
+		"_notes.go":                "package main\n",
+	}
+	plan, err := testPlan(t, manifest, files, dependencyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"README.md", "assets/images/player.png", "main.go", "scenes/scene.go", "sounds/sounds.go"}
+	if got := plannedPaths(plan.Files); !slices.Equal(got, want) {
+		t.Errorf("planned files %q, want %q", got, want)
+	}
+	byPath := map[string]plannedFile{}
+	for _, file := range plan.Files {
+		byPath[file.Path] = file
+	}
+
+	// The common file replaces the template's own copy
+	if common := byPath["sounds/sounds.go"]; common.CommonFile != "templates/common/sounds/sounds.go" {
+		t.Errorf("sounds/sounds.go comes from %q, want the common file", common.CommonFile)
+	}
+	wantSkipped := []skippedFile{{Path: "sounds/sounds.go", Source: "testdata/game/sounds/sounds.go", OverriddenBy: "templates/common/sounds/sounds.go"}}
+	if !slices.Equal(plan.Skipped, wantSkipped) {
+		t.Errorf("skipped %+v, want %+v", plan.Skipped, wantSkipped)
+	}
+
+	rewrite := []importRewrite{{From: "github.com/TheBitDrifter/bappacreate/templates/game/scenes", To: "github.com/me/game/scenes"}}
+	for _, name := range []string{"main.go", "README.md"} {
+		if got := byPath[name].Rewrites; !slices.Equal(got, rewrite) {
+			t.Errorf("%s rewrites %+v, want %+v", name, got, rewrite)
+		}
+	}
+	if png := byPath["assets/images/player.png"]; !png.Binary || png.Rewrites != nil {
+		t.Errorf("the image is planned as %+v, want a binary file without rewrites", png)
+	}
+	wantCommands := []string{"go mod init github.com/me/game", "go get github.com/TheBitDrifter/bappa/coldbrew@latest"}
+	if !slices.Equal(plan.Commands, wantCommands) {
+		t.Errorf("commands %q, want %q", plan.Commands, wantCommands)
+	}
+	for _, dir := range []string{"assets/images", "assets/sounds", "scenes"} {
+		if !slices.Contains(plan.Directories, dir) {
+			t.Errorf("directories %q don't include %s", plan.Directories, dir)
+		}
+	}
+
+	// The JSON form carries where every file comes from
+	var out bytes.Buffer
+	if err := printPlanJSON(&out, plan); err != nil {
+		t.Fatal(err)
+	}
+	var decoded projectPlan
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("the JSON plan doesn't parse: %v\n%s", err, out.Bytes())
+	}
+	if !slices.Equal(plannedPaths(decoded.Files), want) || !slices.Equal(decoded.Skipped, wantSkipped) || decoded.Files[4].CommonFile == "" {
+		t.Errorf("the JSON plan lost files, skipped files or their origin:\n%s", out.Bytes())
+	}
+
+	out.Reset()
+	printPlan(&out, plan)
+	for _, line := range []string{
+		"  sounds/sounds.go (common file templates/common/sounds/sounds.go)",
+		"      import github.com/TheBitDrifter/bappacreate/templates/game/scenes => github.com/me/game/scenes",
+		"  sounds/sounds.go (overridden by common file templates/common/sounds/sounds.go)",
+		"  go mod init github.com/me/game",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("the plan has no line %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestBuildPlanErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		deps  dependencyOptions
+		err   []string
+	}{
+		{
+			name:  "every broken file is reported",
+			files: map[string]string{"main.go": "package main\nimport (", "scenes/scene.go": "package", "ok.go": "package main\n"},
+			err:   []string{"testdata/game/main.go:", "testdata/game/scenes/scene.go:"},
+		},
+		{
+			name:  "version of a template with its own modules",
+			files: map[string]string{"client/go.mod": "module x/client\n", "client/main.go": "package main\n"},
+			deps:  dependencyOptions{BappaVersion: "v0.1.0"},
+			err:   []string{"--bappa-version is not supported for the game template"},
+		},
+	}
+	for _, tt := range tests {
+		_, err := testPlan(t, testManifest(), tt.files, tt.deps)
+		if err == nil {
+			t.Errorf("%s: buildPlan succeeded", tt.name)
+			continue
+		}
+		for _, want := range tt.err {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: buildPlan = %v, want an error containing %q", tt.name, err, want)
+			}
+		}
+	}
+}
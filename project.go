@@ -0,0 +1,507 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// project is an existing Bappa project that `add` generators change
+type project struct {
+	Root string // directory of the project's go.mod, or of its go.work when it has several modules
+}
+
+// findProject returns the project dir is in. A module whose parent directory has
+// a go.work, like the modules of a netcode project, belongs to that workspace.
+func findProject(dir string) (*project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		if fileExists(filepath.Join(d, "go.work")) {
+			return &project{Root: d}, nil
+		}
+		if fileExists(filepath.Join(d, "go.mod")) {
+			if parent := filepath.Dir(d); fileExists(filepath.Join(parent, "go.work")) {
+				return &project{Root: parent}, nil
+			}
+			return &project{Root: d}, nil
+		}
+		if filepath.Dir(d) == d {
+			return nil, fmt.Errorf("no go.mod found in %s or its parents, run bappacreate add inside a project", dir)
+		}
+	}
+}
+
+func fileExists(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir()
+}
+
+// rel returns name relative to the project root, for messages
+func (p *project) rel(name string) string {
+	if rel, err := filepath.Rel(p.Root, name); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return name
+}
+
+// goFiles returns every Go file of the project, leaving out tests and the
+// directories the go tool ignores
+func (p *project) goFiles() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(p.Root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if name != p.Root && (skipTemplateFile(entry.Name()) || entry.Name() == "vendor" || entry.Name() == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") && !skipTemplateFile(entry.Name()) {
+			files = append(files, name)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// findVar returns the file declaring the package level variable name
+func (p *project) findVar(name string) (string, error) {
+	files, err := p.goFiles()
+	if err != nil {
+		return "", err
+	}
+
+	var found []string
+	for _, file := range files {
+		goFile, err := parseGoFile(file)
+		if err != nil {
+			return "", err
+		}
+		if goFile.findVar(name) != nil {
+			found = append(found, file)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("%s is not declared anywhere in %s", name, p.Root)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("%s is declared in %s and %s", name, p.rel(found[0]), p.rel(found[1]))
+}
+
+// findCalls returns the files of package main calling a method called method
+func (p *project) findCalls(method string) ([]string, error) {
+	files, err := p.goFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []string
+	for _, file := range files {
+		goFile, err := parseGoFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if goFile.ast.Name.Name == "main" && len(goFile.findCalls(method)) > 0 {
+			found = append(found, file)
+		}
+	}
+	return found, nil
+}
+
+// importPath returns the import path of the package in dir, read from the go.mod
+// of the module dir is in
+func (p *project) importPath(dir string) (string, error) {
+	for moduleDir := dir; ; moduleDir = filepath.Dir(moduleDir) {
+		data, err := os.ReadFile(filepath.Join(moduleDir, "go.mod"))
+		if err == nil {
+			modulePath := modfile.ModulePath(data)
+			if modulePath == "" {
+				return "", fmt.Errorf("%s: no module path", p.rel(filepath.Join(moduleDir, "go.mod")))
+			}
+			rel, err := filepath.Rel(moduleDir, dir)
+			if err != nil {
+				return "", err
+			}
+			if rel == "." {
+				return modulePath, nil
+			}
+			return modulePath + "/" + filepath.ToSlash(rel), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if moduleDir == p.Root || filepath.Dir(moduleDir) == moduleDir {
+			return "", fmt.Errorf("%s is not inside a Go module", p.rel(dir))
+		}
+	}
+}
+
+// goFile is a parsed Go file of a project
+type goFile struct {
+	name string
+	src  []byte
+	fset *token.FileSet
+	ast  *ast.File
+}
+
+func parseGoFile(name string) (*goFile, error) {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return parseGoSource(name, src)
+}
+
+func parseGoSource(name string, src []byte) (*goFile, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	return &goFile{name: name, src: src, fset: fset, ast: file}, nil
+}
+
+// findVar returns the value of the package level variable name, or nil when the
+// file doesn't declare it
+func (f *goFile) findVar(name string) ast.Expr {
+	for _, decl := range f.ast.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			for i, ident := range valueSpec.Names {
+				if ident.Name == name && i < len(valueSpec.Values) {
+					return valueSpec.Values[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findCalls returns the calls of a method or package function called method
+func (f *goFile) findCalls(method string) []*ast.CallExpr {
+	var calls []*ast.CallExpr
+	ast.Inspect(f.ast, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == method {
+				calls = append(calls, call)
+			}
+		}
+		return true
+	})
+	return calls
+}
+
+// statementOf returns the block holding the statement node is part of, and the
+// index of the statement in it. The block is nil when node isn't in a function body.
+func (f *goFile) statementOf(node ast.Node) (*ast.BlockStmt, int) {
+	path, _ := astutil.PathEnclosingInterval(f.ast, node.Pos(), node.End())
+	for i := 1; i < len(path); i++ {
+		block, ok := path[i].(*ast.BlockStmt)
+		if !ok {
+			continue
+		}
+		for j, stmt := range block.List {
+			if stmt == path[i-1] {
+				return block, j
+			}
+		}
+	}
+	return nil, 0
+}
+
+// text returns the source of node
+func (f *goFile) text(node ast.Node) string {
+	file := f.fset.File(node.Pos())
+	return string(f.src[file.Offset(node.Pos()):file.Offset(node.End())])
+}
+
+// offset returns the offset of pos in the source
+func (f *goFile) offset(pos token.Pos) int {
+	return f.fset.File(pos).Offset(pos)
+}
+
+// appendElement returns the source with element added as the last element of the
+// list between the brackets at open and close, like a composite literal or the
+// arguments of a call. Lists spanning several lines get the element on a line of
+// its own. The result is gofmt'ed.
+func (f *goFile) appendElement(open, close token.Pos, elements []ast.Expr, element string) ([]byte, error) {
+	for _, existing := range elements {
+		if f.text(existing) == element {
+			return nil, fmt.Errorf("%s already lists %s", filepath.Base(f.name), element)
+		}
+	}
+
+	file := f.fset.File(open)
+	closeLine := file.Line(close)
+	lastLine := file.Line(open)
+	if len(elements) > 0 {
+		lastLine = file.Line(elements[len(elements)-1].End())
+	}
+
+	var offset int
+	var insert string
+	switch {
+	case lastLine < closeLine:
+		// Own line, right before the line of the closing bracket
+		offset = file.Offset(file.LineStart(closeLine))
+		insert = element + ",\n"
+	case len(elements) == 0:
+		// An empty list, like []coldbrew.RenderSystem{}, is split over lines
+		offset = f.offset(close)
+		insert = "\n" + element + ",\n"
+	default:
+		offset = f.offset(close)
+		before := bytes.TrimRight(f.src[:offset], " \t\n")
+		insert = element
+		if len(elements) > 0 && !bytes.HasSuffix(before, []byte(",")) {
+			insert = ", " + element
+		}
+	}
+
+	return f.insert(offset, insert)
+}
+
+// insert returns the gofmt'ed source with text inserted at offset
+func (f *goFile) insert(offset int, text string) ([]byte, error) {
+	src := make([]byte, 0, len(f.src)+len(text))
+	src = append(src, f.src[:offset]...)
+	src = append(src, text...)
+	src = append(src, f.src[offset:]...)
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("changing %s: %v", f.name, err)
+	}
+	return formatted, nil
+}
+
+// addImport returns the source with importPath imported
+func (f *goFile) addImport(importPath string) ([]byte, error) {
+	if !astutil.AddImport(f.fset, f.ast, importPath) {
+		return f.src, nil
+	}
+	var out bytes.Buffer
+	if err := format.Node(&out, f.fset, f.ast); err != nil {
+		return nil, fmt.Errorf("formatting %s: %v", f.name, err)
+	}
+	return out.Bytes(), nil
+}
+
+// importName returns the name the file refers to importPath by, or "" when it
+// doesn't import it
+func (f *goFile) importName(importPath string) string {
+	for _, spec := range f.ast.Imports {
+		if unquoted, err := strconv.Unquote(spec.Path.Value); err != nil || unquoted != importPath {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+		return packageNameOf(importPath)
+	}
+	return ""
+}
+
+// packageNameOf returns the name a package is usually imported as, the last
+// element of its import path that isn't a major version, e.g. "ebiten" for
+// github.com/hajimehoshi/ebiten/v2
+func packageNameOf(importPath string) string {
+	dir, base := path.Split(importPath)
+	if len(base) > 1 && base[0] == 'v' && strings.Trim(base[1:], "0123456789") == "" && dir != "" {
+		return path.Base(dir)
+	}
+	return base
+}
+
+// importsName reports whether the file imports a package as name
+func (f *goFile) importsName(name string) bool {
+	for _, spec := range f.ast.Imports {
+		if importPath, err := strconv.Unquote(spec.Path.Value); err == nil && f.importName(importPath) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// declares reports whether the file declares name at package level
+func (f *goFile) declares(name string) bool {
+	for _, decl := range f.ast.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.Name == name {
+				return true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.Name == name {
+						return true
+					}
+				case *ast.ValueSpec:
+					for _, ident := range spec.Names {
+						if ident.Name == name {
+							return true
+						}
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// projectChanges are the files a generator creates and updates. Nothing is
+// written until every change has been worked out, so a generator that fails
+// halfway leaves the project as it was.
+type projectChanges struct {
+	project *project
+	files   []changedFile
+}
+
+type changedFile struct {
+	Name    string
+	Content []byte
+	Created bool
+}
+
+// create adds a new file, failing if it already exists
+func (c *projectChanges) create(name string, content []byte) error {
+	if _, err := os.Lstat(name); err == nil {
+		return fmt.Errorf("%s already exists", c.project.rel(name))
+	}
+	for _, file := range c.files {
+		if file.Name == name {
+			return fmt.Errorf("%s is created twice", c.project.rel(name))
+		}
+	}
+	c.files = append(c.files, changedFile{Name: name, Content: content, Created: true})
+	return nil
+}
+
+// update replaces the content of an existing file, or of a file created or
+// updated earlier
+func (c *projectChanges) update(name string, content []byte) {
+	for i, file := range c.files {
+		if file.Name == name {
+			c.files[i].Content = content
+			return
+		}
+	}
+	c.files = append(c.files, changedFile{Name: name, Content: content})
+}
+
+// content returns the content of name with the changes made so far
+func (c *projectChanges) content(name string) ([]byte, error) {
+	for _, file := range c.files {
+		if file.Name == name {
+			return file.Content, nil
+		}
+	}
+	return os.ReadFile(name)
+}
+
+// parse parses name with the changes made so far
+func (c *projectChanges) parse(name string) (*goFile, error) {
+	src, err := c.content(name)
+	if err != nil {
+		return nil, err
+	}
+	return parseGoSource(name, src)
+}
+
+// apply writes the changes and reports each file
+func (c *projectChanges) apply() error {
+	for _, file := range c.files {
+		if file.Created {
+			if err := os.MkdirAll(filepath.Dir(file.Name), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(file.Name, file.Content, 0644); err != nil {
+				return err
+			}
+			fmt.Printf("Created %s\n", c.project.rel(file.Name))
+			continue
+		}
+
+		info, err := os.Stat(file.Name)
+		if err != nil {
+			return err
+		}
+		// Written next to the file and renamed so an interrupted write can't truncate it
+		tmp := file.Name + ".bappacreate"
+		if err := os.WriteFile(tmp, file.Content, info.Mode().Perm()); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, file.Name); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		fmt.Printf("Updated %s\n", c.project.rel(file.Name))
+	}
+	return nil
+}
+
+// findFile returns the project file whose path ends in name, e.g.
+// "components/components.go"
+func (p *project) findFile(name string) (string, error) {
+	files, err := p.goFiles()
+	if err != nil {
+		return "", err
+	}
+
+	var found []string
+	for _, file := range files {
+		if rel := p.rel(file); rel == name || strings.HasSuffix(rel, "/"+name) {
+			found = append(found, file)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no %s found in %s", name, p.Root)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("found %s and %s", p.rel(found[0]), p.rel(found[1]))
+}
+
+// declaredIn returns the Go file in dir declaring name at package level, or ""
+func declaredIn(dir, name string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		goFile, err := parseGoFile(file)
+		if err != nil {
+			return "", err
+		}
+		if goFile.declares(name) {
+			return file, nil
+		}
+	}
+	return "", nil
+}
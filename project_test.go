@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// generatorTest is a case of an add generator, run on a project generated
+// offline from opts
+type generatorTest struct {
+	name    string
+	opts    projectOptions
+	prepare func(t *testing.T, proj *project) // changes the project before add, if set
+	add     func(proj *project) (*projectChanges, error)
+
+	// The project relative files add writes, no others, with the import paths
+	// and the code they must contain
+	imports map[string][]string
+	code    map[string][]string
+}
+
+// runGeneratorTests runs each test's generator on a new project and checks the
+// files it writes are gofmt clean and import and contain what the test lists,
+// then that adding the same thing again is rejected as a duplicate
+func runGeneratorTests(t *testing.T, tests []generatorTest) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proj := generateTestProject(t, tt.opts)
+			if tt.prepare != nil {
+				tt.prepare(t, proj)
+			}
+
+			changes, err := tt.add(proj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := changes.apply(); err != nil {
+				t.Fatalf("applying the changes: %v", err)
+			}
+
+			var names []string
+			for _, file := range changes.files {
+				name := proj.rel(file.Name)
+				names = append(names, name)
+				checkGeneratedFile(t, name, file.Content, tt.imports[name], tt.code[name])
+			}
+			want := slices.Collect(maps.Keys(tt.imports))
+			for name := range tt.code {
+				if !slices.Contains(want, name) {
+					want = append(want, name)
+				}
+			}
+			slices.Sort(names)
+			slices.Sort(want)
+			if !slices.Equal(names, want) {
+				t.Errorf("changed files %q, want %q", names, want)
+			}
+
+			if _, err := tt.add(proj); err == nil || !strings.Contains(err.Error(), "already") {
+				t.Errorf("adding it again = %v, want it rejected as a duplicate", err)
+			}
+		})
+	}
+}
+
+// generateTestProject generates me/game offline from opts in a temporary
+// directory, which becomes the working directory, and returns the project
+func generateTestProject(t *testing.T, opts projectOptions) *project {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	opts.Name = "me/game"
+	opts.NoHooks = true
+	opts.Deps.Offline = true
+	if err := createProject(opts); err != nil {
+		t.Fatalf("generating the project: %v", err)
+	}
+	proj, err := findProject("game")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proj
+}
+
+// checkGeneratedFile fails t unless the Go file name is gofmt clean, imports
+// each of imports and contains each of code
+func checkGeneratedFile(t *testing.T, name string, content []byte, imports, code []string) {
+	t.Helper()
+	formatted, err := format.Source(content)
+	if err != nil {
+		t.Errorf("%s is not valid Go: %v\n%s", name, err, content)
+		return
+	}
+	if !bytes.Equal(formatted, content) {
+		t.Errorf("%s is not gofmt clean:\n%s", name, content)
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), name, content, parser.ImportsOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		paths = append(paths, path)
+	}
+	for _, path := range imports {
+		if !slices.Contains(paths, path) {
+			t.Errorf("%s doesn't import %s:\n%s", name, path, content)
+		}
+	}
+	for _, want := range code {
+		if !containsCode(string(content), want) {
+			t.Errorf("%s has no %s:\n%s", name, want, content)
+		}
+	}
+}
+
+// containsCode reports whether src contains code, however gofmt aligned it
+func containsCode(src, code string) bool {
+	return strings.Contains(strings.Join(strings.Fields(src), " "), code)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Template files ending in this suffix are rendered with text/template and
+// written without it, e.g. main.go.tmpl becomes main.go
+const templateFileSuffix = ".tmpl"
+
+// templateData is what `.tmpl` files are rendered with, e.g. {{.Title}} or
+// {{if .HasFeature "split"}}
+type templateData struct {
+	ProjectName string     // project directory name
+	ModulePath  string     // Go module path of the project
+	Identifier  string     // Go identifier safe form of the project name
+	Title       string     // human readable project name, e.g. for window titles
+	Author      string     // may be empty
+	Template    string     // name of the template the project is generated from
+	Resolution  Resolution // default window size
+	Features    []string   // features of the template, e.g. split, ldtk
+}
+
+// HasFeature reports whether the project was generated with feature
+func (d templateData) HasFeature(feature string) bool {
+	for _, f := range d.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// isTemplateFile reports whether the template file at sourcePath is rendered
+func isTemplateFile(sourcePath string) bool {
+	return strings.HasSuffix(sourcePath, templateFileSuffix)
+}
+
+// renderTemplate executes content as a text/template with data
+func renderTemplate(name string, content []byte, data templateData) ([]byte, error) {
+	tmpl, err := template.New(name).Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("rendering %s: %v", name, err)
+	}
+	return out.Bytes(), nil
+}
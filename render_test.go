@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	data := templateData{
+		ProjectName: "space-game",
+		ModulePath:  "example.com/space-game",
+		Identifier:  "spacegame",
+		Title:       `Space "Game"`,
+		Template:    "topdown",
+		Resolution:  Resolution{Width: 640, Height: 360},
+		Features:    []string{"split"},
+	}
+	tests := []struct {
+		content string
+		want    string
+		err     string
+	}{
+		{content: `client.SetTitle({{printf "%q" .Title}})`, want: `client.SetTitle("Space \"Game\"")`},
+		{content: "{{.Resolution.Width}}x{{.Resolution.Height}} {{.Resolution}}", want: "640x360 640x360"},
+		{content: "# {{.ProjectName}} ({{.ModulePath}}, {{.Identifier}}, {{.Template}})", want: "# space-game (example.com/space-game, spacegame, topdown)"},
+		{content: `{{if .HasFeature "split"}}split{{end}}{{if .HasFeature "ldtk"}}ldtk{{end}}`, want: "split"},
+		{content: "{{with .Author}}by {{.}}{{else}}anonymous{{end}}", want: "anonymous"},
+		{content: "{{.Title", err: "main.go.tmpl"},
+		{content: "{{.Missing}}", err: "rendering main.go.tmpl"},
+		{content: `{{template "other"}}`, err: "rendering main.go.tmpl"},
+	}
+	for _, tt := range tests {
+		got, err := renderTemplate("main.go.tmpl", []byte(tt.content), data)
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("renderTemplate(%s) = %q, %v, want an error containing %q", tt.content, got, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("renderTemplate(%s): %v", tt.content, err)
+		case string(got) != tt.want:
+			t.Errorf("renderTemplate(%s) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+// TestPlanRendersTemplateFiles checks that .tmpl files are planned without their
+// suffix, rendered before their imports are rewritten and reported when broken
+func TestPlanRendersTemplateFiles(t *testing.T) {
+	files := map[string]string{
+		"main.go.tmpl": "package main\n\nimport \"{{.ModulePath}}/scenes\"\n\nconst title = {{printf \"%q\" .Title}}\n\nvar _ = scenes.X\n",
+		"README.md":    "{{.Title}} is not rendered\n",
+	}
+	plan, err := testPlan(t, testManifest(), files, dependencyOptions{Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]plannedFile{}
+	for _, file := range plan.Files {
+		byPath[file.Path] = file
+	}
+	if _, ok := byPath["main.go.tmpl"]; ok || !byPath["main.go"].Rendered || byPath["README.md"].Rendered {
+		t.Fatalf("planned %q, want main.go rendered and README.md copied", plannedPaths(plan.Files))
+	}
+	main, err := renderFile(plan, byPath["main.go"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(main), "import \"github.com/me/game/scenes\"\n") || !strings.Contains(string(main), `const title = "Game"`) {
+		t.Errorf("rendered main.go:\n%s", main)
+	}
+	readme, err := renderFile(plan, byPath["README.md"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(readme) != files["README.md"] {
+		t.Errorf("README.md was rendered:\n%s", readme)
+	}
+
+	_, err = testPlan(t, testManifest(), map[string]string{"main.go.tmpl": "package main // {{.Missing"}, dependencyOptions{Offline: true})
+	if err == nil || !strings.Contains(err.Error(), "testdata/game/main.go.tmpl") {
+		t.Errorf("planning a broken template = %v, want an error naming it", err)
+	}
+}
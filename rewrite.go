@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// rewriteImportPath returns the project import path for an import of the template or
+// common packages, or importPath unchanged when it points anywhere else
+func rewriteImportPath(importPath string, manifest *TemplateManifest, projectImportPath string) string {
+	builtinPath := builtinImportPath(manifest.Name)
+
+	// Common packages the template inherits
+	for commonPath, tempPath := range manifest.commonImportMappings() {
+		if rewritten, ok := replacePathPrefix(importPath, commonPath, tempPath); ok {
+			importPath = rewritten
+			break
+		}
+	}
+
+	// Common import path replacement
+	if rewritten, ok := replacePathPrefix(importPath, commonImportPattern, builtinPath); ok {
+		importPath = rewritten
+	}
+
+	// Common files use the built-in template path, template files the template's import path
+	for _, templatePath := range []string{builtinPath, manifest.SourceImportPath()} {
+		if rewritten, ok := replacePathPrefix(importPath, templatePath, projectImportPath); ok {
+			return rewritten
+		}
+	}
+	return importPath
+}
+
+// replacePathPrefix replaces the leading old elements of p with new. It only
+// matches whole path elements, so "templates/platformer" doesn't match
+// "templates/platformer-split".
+func replacePathPrefix(p, old, new string) (string, bool) {
+	if p == old {
+		return new, true
+	}
+	if strings.HasPrefix(p, old+"/") {
+		return new + p[len(old):], true
+	}
+	return p, false
+}
+
+// rewriteGoSource points the imports of a template Go file at the project and,
+// when packageName isn't empty, renames its package. Only import specs are
+// touched, string literals and comments mentioning template paths are left alone.
+func rewriteGoSource(filename string, src []byte, manifest *TemplateManifest, projectImportPath, packageName string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid import path %s", fset.Position(spec.Pos()), spec.Path.Value)
+		}
+		if rewritten := rewriteImportPath(importPath, manifest, projectImportPath); rewritten != importPath {
+			astutil.RewriteImport(fset, file, importPath, rewritten)
+		}
+	}
+
+	if packageName != "" {
+		file.Name.Name = packageName
+	}
+
+	var out bytes.Buffer
+	if err := format.Node(&out, fset, file); err != nil {
+		return nil, fmt.Errorf("formatting %s: %v", filename, err)
+	}
+	return out.Bytes(), nil
+}
+
+// goImportRewrites lists the imports of a template Go file that rewriteGoSource changes
+func goImportRewrites(filename string, src []byte, manifest *TemplateManifest, projectImportPath string) ([]importRewrite, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	var rewrites []importRewrite
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid import path %s", filename, spec.Path.Value)
+		}
+		if rewritten := rewriteImportPath(importPath, manifest, projectImportPath); rewritten != importPath {
+			rewrites = append(rewrites, importRewrite{From: importPath, To: rewritten})
+		}
+	}
+	return rewrites, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplacePathPrefix(t *testing.T) {
+	tests := []struct {
+		p, old, new string
+		want        string
+		ok          bool
+	}{
+		{p: "a/b", old: "a/b", new: "x", want: "x", ok: true},
+		{p: "a/b/c", old: "a/b", new: "x", want: "x/c", ok: true},
+		{p: "a/bc", old: "a/b", new: "x", want: "a/bc"},
+		{p: "templates/platformer-split/scenes", old: "templates/platformer", new: "x", want: "templates/platformer-split/scenes"},
+		{p: "a", old: "a/b", new: "x", want: "a"},
+	}
+	for _, tt := range tests {
+		got, ok := replacePathPrefix(tt.p, tt.old, tt.new)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("replacePathPrefix(%q, %q, %q) = %q, %v, want %q, %v", tt.p, tt.old, tt.new, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRewriteImportPath(t *testing.T) {
+	manifest := testManifest()
+	manifest.Common = []CommonPackage{{Package: "coresystems", Dest: "systems/core"}}
+	tests := []struct{ importPath, want string }{
+		{importPath: "github.com/TheBitDrifter/bappacreate/templates/game/scenes", want: "example.com/game/scenes"},
+		{importPath: "github.com/TheBitDrifter/bappacreate/templates/game", want: "example.com/game"},
+		{importPath: "github.com/TheBitDrifter/bappacreate/templates/common/coresystems", want: "example.com/game/systems/core"},
+		{importPath: "github.com/TheBitDrifter/bappacreate/templates/common/components", want: "example.com/game/components"},
+		{importPath: "github.com/TheBitDrifter/bappacreate/templates/gamer/scenes", want: "github.com/TheBitDrifter/bappacreate/templates/gamer/scenes"},
+		{importPath: "github.com/TheBitDrifter/bappa/coldbrew", want: "github.com/TheBitDrifter/bappa/coldbrew"},
+	}
+	for _, tt := range tests {
+		if got := rewriteImportPath(tt.importPath, manifest, "example.com/game"); got != tt.want {
+			t.Errorf("rewriteImportPath(%q) = %q, want %q", tt.importPath, got, tt.want)
+		}
+	}
+
+	// Templates from elsewhere import their packages by their own path
+	external := testManifest()
+	external.ImportPath = "git.example.com/studio/templates/arena"
+	if got := rewriteImportPath("git.example.com/studio/templates/arena/scenes", external, "example.com/game"); got != "example.com/game/scenes" {
+		t.Errorf("rewriteImportPath of an external template's package = %q", got)
+	}
+}
+
+func TestRewriteGoSource(t *testing.T) {
+	const src = `package coresystems
+
+import (
+	"fmt"
+
+	"github.com/TheBitDrifter/bappacreate/templates/game/components"
+	scn "github.com/TheBitDrifter/bappacreate/templates/game/scenes"
+)
+
+// Mentions of github.com/TheBitDrifter/bappacreate/templates/game stay as they are
+const docs = "github.com/TheBitDrifter/bappacreate/templates/game/scenes"
+
+func f() { fmt.Println(components.X, scn.Y, docs) }
+`
+	const want = `package systems
+
+import (
+	"fmt"
+
+	"example.com/game/components"
+	scn "example.com/game/scenes"
+)
+
+// Mentions of github.com/TheBitDrifter/bappacreate/templates/game stay as they are
+const docs = "github.com/TheBitDrifter/bappacreate/templates/game/scenes"
+
+func f() { fmt.Println(components.X, scn.Y, docs) }
+`
+	got, err := rewriteGoSource("systems/a.go", []byte(src), testManifest(), "example.com/game", "systems")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("rewriteGoSource =\n%s\nwant\n%s", got, want)
+	}
+
+	// The package clause is kept without a package name
+	got, err = rewriteGoSource("systems/a.go", []byte(src), testManifest(), "example.com/game", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(got), "package coresystems\n") {
+		t.Errorf("rewriteGoSource renamed the package:\n%s", got)
+	}
+
+	if _, err := rewriteGoSource("broken.go", []byte("package main\nimport ("), testManifest(), "example.com/game", ""); err == nil || !strings.Contains(err.Error(), "broken.go:") {
+		t.Errorf("rewriteGoSource of invalid Go = %v, want the parse error", err)
+	}
+}
+
+func TestRewriteTemplateImports(t *testing.T) {
+	manifest := testManifest()
+	manifest.Common = []CommonPackage{{Package: "coresystems", Dest: "systems/core"}}
+	const content = "go run github.com/TheBitDrifter/bappacreate/templates/game/tools\n" +
+		"see github.com/TheBitDrifter/bappacreate/templates/common/coresystems\n"
+	const want = "go run example.com/game/tools\nsee example.com/game/systems/core\n"
+	if got := rewriteTemplateImports(content, manifest, "example.com/game"); got != want {
+		t.Errorf("rewriteTemplateImports =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//go:generate go run gen_archives.go
+
+// templateSource is where the files of a template are read from. Every source
+// goes through the same generation engine, so the rewrite, skip and error rules
+// don't depend on where a template lives.
+type templateSource interface {
+	// Files returns the template's files, rooted at the template directory
+	Files() (fs.FS, error)
+	// Location names the source in plans and error messages
+	Location() string
+}
+
+// embeddedTemplate is a template directory embedded in templateFS
+type embeddedTemplate struct {
+	name string
+}
+
+func (t embeddedTemplate) Files() (fs.FS, error) {
+	return fs.Sub(templateFS, t.Location())
+}
+
+func (t embeddedTemplate) Location() string {
+	return path.Join("templates", t.name)
+}
+
+// archivedTemplate is a template embedded as templates/<name>.zip because it
+// contains nested Go modules, which //go:embed skips
+type archivedTemplate struct {
+	name string
+}
+
+func (t archivedTemplate) Files() (fs.FS, error) {
+	data, err := templateFS.ReadFile(t.Location())
+	if err != nil {
+		return nil, fmt.Errorf("template %s is not embedded (run 'go generate' and rebuild bappacreate): %v", t.name, err)
+	}
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+func (t archivedTemplate) Location() string {
+	return path.Join("templates", t.name+".zip")
+}
+
+// embeddedSource returns the source of the embedded template name
+func embeddedSource(name string) templateSource {
+	if _, err := fs.Stat(templateFS, archivedTemplate{name}.Location()); err == nil {
+		return archivedTemplate{name}
+	}
+	return embeddedTemplate{name}
+}
+
+// skipTemplateFile reports whether a file or directory is left out of generated
+// projects. These are the names //go:embed leaves out, so embedded and archived
+// templates produce the same files.
+func skipTemplateFile(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// dirTemplate is a template directory on disk, from --template-dir, --template-repo
+// or the user template directory
+type dirTemplate struct {
+	dir string
+}
+
+func (t dirTemplate) Files() (fs.FS, error) {
+	return os.DirFS(t.dir), nil
+}
+
+func (t dirTemplate) Location() string {
+	return t.dir
+}
+
+// availableTemplate is a template that can be generated
+type availableTemplate struct {
+	Manifest *TemplateManifest
+	Source   templateSource
+}
+
+// templateOptions select where templates are looked up
+type templateOptions struct {
+	Dir  string // --template-dir, a template or a directory of templates
+	Repo string // --template-repo, <url>[@<ref>] of a git repository
+}
+
+// userTemplateDir returns the directory templates installed for the current
+// user are read from, e.g. ~/.config/bappacreate/templates
+func userTemplateDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "bappacreate", "templates"), nil
+}
+
+// loadTemplates returns the templates selected by opts, sorted by name. Without
+// --template-dir or --template-repo these are the user's templates and the built-in
+// ones, with a user template replacing a built-in template of the same name. The
+// returned cleanup removes any cloned repository once generation is done.
+func loadTemplates(opts templateOptions) ([]availableTemplate, func(), error) {
+	cleanup := func() {}
+
+	switch {
+	case opts.Dir != "" && opts.Repo != "":
+		return nil, cleanup, fmt.Errorf("--template-dir and --template-repo cannot be used together")
+	case opts.Dir != "":
+		templates, err := loadTemplateDir(opts.Dir)
+		return templates, cleanup, err
+	case opts.Repo != "":
+		dir, err := cloneTemplateRepo(opts.Repo)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		cleanup = func() { os.RemoveAll(dir) }
+		templates, err := loadTemplateDir(dir)
+		if err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("%s: %w", opts.Repo, err)
+		}
+		return templates, cleanup, nil
+	}
+
+	manifests, err := loadManifests(templateFS, "templates")
+	if err != nil {
+		return nil, cleanup, err
+	}
+	byName := map[string]availableTemplate{}
+	for _, manifest := range manifests {
+		byName[manifest.Name] = availableTemplate{Manifest: manifest, Source: embeddedSource(manifest.Name)}
+	}
+
+	if dir, err := userTemplateDir(); err == nil {
+		if _, err := os.Stat(dir); err == nil {
+			userTemplates, err := loadTemplateDir(dir)
+			if err != nil {
+				return nil, cleanup, err
+			}
+			for _, template := range userTemplates {
+				byName[template.Manifest.Name] = template
+			}
+		}
+	}
+
+	templates := make([]availableTemplate, 0, len(byName))
+	for _, template := range byName {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Manifest.Name < templates[j].Manifest.Name
+	})
+	return templates, cleanup, nil
+}
+
+// loadTemplateDir reads the templates in dir. dir is either a single template (it
+// has a template.json), a repository with a templates/ directory, or a directory
+// of templates.
+func loadTemplateDir(dir string) ([]availableTemplate, error) {
+	fsys := os.DirFS(dir)
+	if _, err := fs.Stat(fsys, manifestFileName); err == nil {
+		manifest, err := readManifest(fsys, ".")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+		return []availableTemplate{{Manifest: manifest, Source: dirTemplate{dir}}}, nil
+	}
+
+	root := "."
+	if info, err := fs.Stat(fsys, "templates"); err == nil && info.IsDir() {
+		root = "templates"
+	}
+	manifests, err := loadManifests(fsys, root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("no templates found in %s", dir)
+	}
+
+	templates := make([]availableTemplate, len(manifests))
+	for i, manifest := range manifests {
+		templateDir := filepath.Join(dir, filepath.FromSlash(root), manifest.Name)
+		templates[i] = availableTemplate{Manifest: manifest, Source: dirTemplate{templateDir}}
+	}
+	return templates, nil
+}
+
+// sourceName describes where a template comes from in `list`
+func sourceName(source templateSource) string {
+	switch source.(type) {
+	case embeddedTemplate, archivedTemplate:
+		return "built-in"
+	}
+	return source.Location()
+}
+
+// findTemplate returns the template called name. An empty name picks the only
+// template of a --template-dir or --template-repo, or the default template.
+func findTemplate(name string, templates []availableTemplate) (availableTemplate, error) {
+	if name == "" {
+		if len(templates) == 1 {
+			return templates[0], nil
+		}
+		name = defaultTemplate
+	}
+
+	names := make([]string, len(templates))
+	for i, template := range templates {
+		if template.Manifest.Name == name {
+			return template, nil
+		}
+		names[i] = template.Manifest.Name
+	}
+	return availableTemplate{}, fmt.Errorf("template '%s' not found (available: %s)", name, strings.Join(names, ", "))
+}
+
+// splitRepoRef splits a --template-repo value into its URL and optional ref. The
+// ref follows the last '@', unless that '@' belongs to an scp-like URL such as
+// git@github.com:studio/templates.git.
+func splitRepoRef(repo string) (url, ref string) {
+	i := strings.LastIndex(repo, "@")
+	if i < 0 || strings.Contains(repo[i+1:], ":") {
+		return repo, ""
+	}
+	return repo[:i], repo[i+1:]
+}
+
+// cloneTemplateRepo clones a --template-repo into a temporary directory and
+// checks out its ref. file:// URLs work without network access.
+func cloneTemplateRepo(repo string) (string, error) {
+	url, ref := splitRepoRef(repo)
+	if url == "" {
+		return "", fmt.Errorf("invalid --template-repo %q", repo)
+	}
+
+	dir, err := os.MkdirTemp("", "bappacreate-template-*")
+	if err != nil {
+		return "", err
+	}
+
+	// Progress goes to stderr so `new --dry-run --json` and `list --json` stay parseable
+	fmt.Fprintf(os.Stderr, "Fetching templates from %s\n", repo)
+	if err := gitClone(url, ref, dir); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("fetching %s: %v", repo, err)
+	}
+	return dir, nil
+}
+
+// gitClone clones url into dir. A shallow clone is enough for branches and tags,
+// other refs such as commit hashes need the full history.
+func gitClone(url, ref, dir string) error {
+	if ref == "" {
+		return runGit("", "clone", "--quiet", "--depth", "1", url, dir)
+	}
+	if err := runGit("", "clone", "--quiet", "--depth", "1", "--branch", ref, url, dir); err == nil {
+		return nil
+	}
+
+	// The failed clone may have left files behind
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := runGit("", "clone", "--quiet", "--no-checkout", url, dir); err != nil {
+		return err
+	}
+	return runGit(dir, "checkout", "--quiet", ref)
+}
+
+// runGit runs git quietly, returning its output as the error when it fails
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %v\n%s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedSource(t *testing.T) {
+	if _, ok := embeddedSource("platformer-netcode").(archivedTemplate); !ok {
+		t.Error("the netcode template is not read from its archive")
+	}
+	if _, ok := embeddedSource("topdown").(embeddedTemplate); !ok {
+		t.Error("the topdown template is not read from templates/topdown")
+	}
+
+	_, err := archivedTemplate{"missing"}.Files()
+	if err == nil || !strings.Contains(err.Error(), "template missing is not embedded (run 'go generate' and rebuild bappacreate)") {
+		t.Errorf("reading a missing archive = %v", err)
+	}
+}
+
+// TestArchiveUpToDate checks that the embedded netcode archive holds exactly the
+// files of templates/platformer-netcode, so a forgotten `go generate` fails here
+func TestArchiveUpToDate(t *testing.T) {
+	archive, err := archivedTemplate{"platformer-netcode"}.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archived := map[string]string{}
+	err = fs.WalkDir(archive, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(archive, name)
+		archived[name] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join("templates", "platformer-netcode")
+	onDisk := readTree(t, dir)
+	for name, content := range onDisk {
+		if got, ok := archived[name]; !ok {
+			t.Errorf("%s is missing from the archive, run go generate", name)
+		} else if got != content {
+			t.Errorf("%s differs from the archive, run go generate", name)
+		}
+	}
+	for name := range archived {
+		if _, ok := onDisk[name]; !ok {
+			t.Errorf("%s is in the archive but not in %s, run go generate", name, dir)
+		}
+	}
+}
+
+// TestSourcesGenerateTheSame plans the built-in templates both from their embedded
+// form and from their directories on disk, which must give the same project
+func TestSourcesGenerateTheSame(t *testing.T) {
+	names, err := resolveProjectNames("me/game", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	templates, cleanup, err := loadTemplates(templateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	for _, name := range []string{"topdown", "platformer-netcode"} {
+		template, err := findTemplate(name, templates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest := template.Manifest
+
+		var contents []map[string]string
+		for _, source := range []templateSource{embeddedSource(name), dirTemplate{filepath.Join("templates", name)}} {
+			plan, err := buildPlan(manifest, source, names, dependencyOptions{Offline: true})
+			if err != nil {
+				t.Fatalf("%s from %s: %v", name, source.Location(), err)
+			}
+			content := map[string]string{}
+			for _, file := range plan.Files {
+				data, err := renderFile(plan, file)
+				if err != nil {
+					t.Fatalf("%s from %s: %v", name, source.Location(), err)
+				}
+				content[file.Path] = string(data)
+			}
+			contents = append(contents, content)
+		}
+		if !maps.Equal(contents[0], contents[1]) {
+			t.Errorf("%s generates %q embedded and %q from disk", name, slices.Sorted(maps.Keys(contents[0])), slices.Sorted(maps.Keys(contents[1])))
+		}
+	}
+}
+
+// TestTemplateSkipRules checks that files //go:embed would leave out are left out
+// of templates on disk too
+func TestTemplateSkipRules(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":          "package main\n",
+		".git/config":      "[core]\n",
+		".env":             "SECRET=1\n",
+		"_drafts/scene.go": "package drafts\n",
+		"scenes/_old.go":   "package scenes\n",
+		"scenes/scene.go":  "package scenes\n",
+	})
+	names, err := resolveProjectNames("me/game", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := buildPlan(testManifest(), dirTemplate{dir}, names, dependencyOptions{Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"main.go", "scenes/scene.go", "go.mod", "go.sum"}
+	if got := plannedPaths(plan.Files); !slices.Equal(got, want) {
+		t.Errorf("planned %q, want %q", got, want)
+	}
+}
+
+// TestWriteProjectReportsEveryFailure checks that writing goes on after a file
+// fails and reports all of them
+func TestWriteProjectReportsEveryFailure(t *testing.T) {
+	plan, err := testPlan(t, testManifest(), map[string]string{"main.go": "package main\n", "a.go": "package main\n", "b.go": "package main\n"}, dependencyOptions{Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sources disappear between planning and writing
+	for i := range plan.Files {
+		if plan.Files[i].Path != "main.go" && plan.Files[i].Generated == "" {
+			plan.Files[i].fsys = fstest.MapFS{}
+		}
+	}
+
+	dir := t.TempDir()
+	_, err = writeProject(plan, dir, writeNew)
+	if err == nil || !strings.Contains(err.Error(), "a.go") || !strings.Contains(err.Error(), "b.go") {
+		t.Errorf("writeProject = %v, want both missing sources reported", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main.go")); err != nil {
+		t.Errorf("the file that could be written wasn't: %v", err)
+	}
+}
+
+// testManifestJSON returns a template.json for a made up template called name
+func testManifestJSON(name string) string {
+	return `{"name": "` + name + `", "description": "a studio template", "modules": ["coldbrew"], "resolution": {"width": 320, "height": 240}}`
+}
+
+func TestLoadTemplateDir(t *testing.T) {
+	single := t.TempDir()
+	writeTree(t, single, map[string]string{"template.json": testManifestJSON("arena"), "main.go": "package main\n"})
+	repo := t.TempDir()
+	writeTree(t, repo, map[string]string{"README.md": "templates\n", "templates/arena/template.json": testManifestJSON("arena"), "templates/maze/template.json": testManifestJSON("maze")})
+	collection := t.TempDir()
+	writeTree(t, collection, map[string]string{"maze/template.json": testManifestJSON("maze"), "notes/todo.txt": "\n"})
+	broken := t.TempDir()
+	writeTree(t, broken, map[string]string{"template.json": `{"name": "arena"}`})
+
+	tests := []struct {
+		dir  string
+		want map[string]string // template directories by name
+		err  string
+	}{
+		{dir: single, want: map[string]string{"arena": single}},
+		{dir: repo, want: map[string]string{"arena": filepath.Join(repo, "templates", "arena"), "maze": filepath.Join(repo, "templates", "maze")}},
+		{dir: collection, want: map[string]string{"maze": filepath.Join(collection, "maze")}},
+		{dir: t.TempDir(), err: "no templates found"},
+		{dir: broken, err: "missing description"},
+		{dir: filepath.Join(single, "missing"), err: "missing"},
+	}
+	for _, tt := range tests {
+		templates, err := loadTemplateDir(tt.dir)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("loadTemplateDir(%s) = %v, want an error containing %q", tt.dir, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("loadTemplateDir(%s): %v", tt.dir, err)
+			continue
+		}
+		got := map[string]string{}
+		for _, template := range templates {
+			got[template.Manifest.Name] = template.Source.Location()
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("loadTemplateDir(%s) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestLoadTemplatesUserDir(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	writeTree(t, filepath.Join(config, "bappacreate", "templates"), map[string]string{
+		"topdown/template.json": testManifestJSON("topdown"),
+		"arena/template.json":   testManifestJSON("arena"),
+	})
+
+	templates, cleanup, err := loadTemplates(templateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	for name, user := range map[string]bool{"topdown": true, "arena": true, "platformer": false} {
+		template, err := findTemplate(name, templates)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := template.Manifest.Description == "a studio template"; got != user {
+			t.Errorf("%s comes from %s, want the user's templates to win over the built-in ones", name, template.Source.Location())
+		}
+	}
+
+	if _, _, err := loadTemplates(templateOptions{Dir: config, Repo: "file:///x"}); err == nil || !strings.Contains(err.Error(), "cannot be used together") {
+		t.Errorf("loadTemplates with a directory and a repository = %v", err)
+	}
+}
+
+func TestSplitRepoRef(t *testing.T) {
+	tests := []struct{ repo, url, ref string }{
+		{repo: "https://example.com/studio/templates.git", url: "https://example.com/studio/templates.git"},
+		{repo: "https://example.com/studio/templates.git@v1.2.0", url: "https://example.com/studio/templates.git", ref: "v1.2.0"},
+		{repo: "git@github.com:studio/templates.git", url: "git@github.com:studio/templates.git"},
+		{repo: "git@github.com:studio/templates.git@main", url: "git@github.com:studio/templates.git", ref: "main"},
+		{repo: "file:///srv/templates@0123abc", url: "file:///srv/templates", ref: "0123abc"},
+	}
+	for _, tt := range tests {
+		if url, ref := splitRepoRef(tt.repo); url != tt.url || ref != tt.ref {
+			t.Errorf("splitRepoRef(%q) = %q, %q, want %q, %q", tt.repo, url, ref, tt.url, tt.ref)
+		}
+	}
+}
+
+// TestTemplateRepo clones a local repository through a file:// URL, at a tag
+// and at a commit, and fails on a ref it doesn't have
+func TestTemplateRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", args[0], err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "--quiet")
+	writeTree(t, repo, map[string]string{"templates/arena/template.json": testManifestJSON("arena")})
+	git("add", ".")
+	git("commit", "--quiet", "-m", "arena")
+	first := git("rev-parse", "HEAD")
+	git("tag", "v1")
+	writeTree(t, repo, map[string]string{"templates/maze/template.json": testManifestJSON("maze")})
+	git("add", ".")
+	git("commit", "--quiet", "-m", "maze")
+
+	url := "file://" + filepath.ToSlash(repo)
+	for ref, want := range map[string]int{"": 2, "@v1": 1, "@" + first: 1} {
+		templates, cleanup, err := loadTemplates(templateOptions{Repo: url + ref})
+		if err != nil {
+			t.Errorf("%s: %v", ref, err)
+			continue
+		}
+		if len(templates) != want {
+			t.Errorf("%s: %d template(s), want %d", ref, len(templates), want)
+		}
+		dir := filepath.Dir(filepath.Dir(templates[0].Source.Location()))
+		cleanup()
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("%s: the clone in %s wasn't removed: %v", ref, dir, err)
+		}
+	}
+
+	if _, _, err := loadTemplates(templateOptions{Repo: url + "@missing"}); err == nil || !strings.Contains(err.Error(), "fetching "+url+"@missing") {
+		t.Errorf("cloning a missing ref = %v", err)
+	}
+}
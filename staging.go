@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// stagingDir is a temporary directory a project is generated into. It is only
+// moved to the project directory once every generation step has succeeded, so
+// a failure never leaves a half-populated project behind.
+type stagingDir struct {
+	Dir       string // where generation writes files
+	target    string // the final project directory
+	committed bool
+}
+
+// newStagingDir creates a staging directory next to target. When target already
+// exists its contents are copied in first, so --force and --merge see the
+// existing files and the swap in Commit keeps everything generation didn't touch.
+func newStagingDir(target string) (*stagingDir, error) {
+	parent, base := filepath.Dir(target), filepath.Base(target)
+
+	dir, err := os.MkdirTemp(parent, "."+base+"-bappacreate-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating staging directory: %v", err)
+	}
+	staging := &stagingDir{Dir: dir, target: target}
+
+	mode := fs.FileMode(0755)
+	if info, err := os.Stat(target); err == nil {
+		mode = info.Mode().Perm()
+		if err := copyTree(target, dir); err != nil {
+			staging.Cleanup()
+			return nil, fmt.Errorf("copying existing project into staging directory: %v", err)
+		}
+	}
+
+	// MkdirTemp creates the directory as 0700
+	if err := os.Chmod(dir, mode); err != nil {
+		staging.Cleanup()
+		return nil, err
+	}
+	return staging, nil
+}
+
+// Commit moves the staged project into place, replacing an existing project directory
+func (s *stagingDir) Commit() error {
+	if _, err := os.Lstat(s.target); os.IsNotExist(err) {
+		if err := os.Rename(s.Dir, s.target); err != nil {
+			return fmt.Errorf("moving project into place: %v", err)
+		}
+		s.committed = true
+		return nil
+	}
+
+	// Move the existing project aside so it can be restored if the swap fails
+	backup := filepath.Join(filepath.Dir(s.target), "."+filepath.Base(s.target)+"-backup-"+filepath.Base(s.Dir))
+	if err := os.Rename(s.target, backup); err != nil {
+		return fmt.Errorf("moving existing project aside: %v", err)
+	}
+	if err := os.Rename(s.Dir, s.target); err != nil {
+		if restoreErr := os.Rename(backup, s.target); restoreErr != nil {
+			return fmt.Errorf("moving project into place: %v (the previous project was left in %s: %v)", err, backup, restoreErr)
+		}
+		return fmt.Errorf("moving project into place: %v", err)
+	}
+	s.committed = true
+
+	if err := os.RemoveAll(backup); err != nil {
+		fmt.Printf("Warning: could not remove %s: %v\n", backup, err)
+	}
+	return nil
+}
+
+// Cleanup removes the staging directory unless it has been committed
+func (s *stagingDir) Cleanup() {
+	if !s.committed {
+		os.RemoveAll(s.Dir)
+	}
+}
+
+// copyTree copies the directory tree at src into the existing directory dst
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(sourcePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, sourcePath)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		targetPath := filepath.Join(dst, relPath)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.Mkdir(targetPath, info.Mode().Perm())
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(sourcePath)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, targetPath)
+		default:
+			return copyFile(sourcePath, targetPath, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(sourcePath, targetPath string, mode fs.FileMode) error {
+	sourceFile, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	targetFile, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(targetFile, sourceFile); err != nil {
+		targetFile.Close()
+		return err
+	}
+	return targetFile.Close()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readTree returns the files under dir by slash separated path
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(name string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, name)
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// writeTree writes files, by slash separated path, under dir
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkOnlyEntry fails t unless name is the only entry of dir, so no staging or
+// backup directory was left behind
+func checkOnlyEntry(t *testing.T, dir, name string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 1 || names[0] != name {
+		t.Errorf("%s holds %q, want only %s", dir, names, name)
+	}
+}
+
+func TestStagingCommit(t *testing.T) {
+	parent := t.TempDir()
+	target := filepath.Join(parent, "game")
+
+	staging, err := newStagingDir(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, staging.Dir, map[string]string{"main.go": "package main\n"})
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("the project exists before the commit: %v", err)
+	}
+	if err := staging.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	staging.Cleanup()
+	if got, want := readTree(t, target), map[string]string{"main.go": "package main\n"}; !maps.Equal(got, want) {
+		t.Errorf("committed project %v, want %v", got, want)
+	}
+	checkOnlyEntry(t, parent, "game")
+
+	// An existing project is staged with its files and replaced as a whole
+	staging, err = newStagingDir(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, staging.Dir, map[string]string{"scenes/scene.go": "package scenes\n"})
+	if err := staging.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"main.go": "package main\n", "scenes/scene.go": "package scenes\n"}
+	if got := readTree(t, target); !maps.Equal(got, want) {
+		t.Errorf("committed project %v, want %v", got, want)
+	}
+	checkOnlyEntry(t, parent, "game")
+}
+
+func TestStagingCleanup(t *testing.T) {
+	parent := t.TempDir()
+	target := filepath.Join(parent, "game")
+	writeTree(t, target, map[string]string{"main.go": "package main\n"})
+
+	staging, err := newStagingDir(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, staging.Dir, map[string]string{"main.go": "package broken\n", "half.go": "package"})
+	staging.Cleanup()
+
+	if got, want := readTree(t, target), map[string]string{"main.go": "package main\n"}; !maps.Equal(got, want) {
+		t.Errorf("the project changed without a commit: %v", got)
+	}
+	checkOnlyEntry(t, parent, "game")
+}
+
+// TestCreateProjectGoCommandFailure checks that a failing go command fails the
+// generation and leaves no project behind
+func TestCreateProjectGoCommandFailure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("not running the go command in -short mode")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOFLAGS", "-mod=mod")
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	err := createProject(projectOptions{Name: "me/game", Template: "topdown", NoHooks: true})
+	if err == nil || !strings.Contains(err.Error(), "setting up Go module") {
+		t.Fatalf("createProject without access to the modules = %v, want the go get failure", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("a failed generation left %s behind", entries[0].Name())
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"log"
 
 	"github.com/TheBitDrifter/bappa/warehouse"
-	"github.com/TheBitDrifter/bappacreate/templates/platformer-ldtk/ldtk"
+	"github.com/TheBitDrifter/bappacreate/templates/platformer/ldtk"
 )
 
 const SCENE_TWO_NAME = "Scene2"
@@ -99,15 +99,6 @@ func NewSkyBackground(sto warehouse.Storage) error {
 		Build()
 }
 
-// NewJazzMusic adds a Jazz music entity
-func NewJazzMusic(sto warehouse.Storage) error {
-	musicArche, err := sto.NewOrExistingArchetype(MusicComposition...)
-	if err != nil {
-		return err
-	}
-	return musicArche.Generate(1, client.NewSoundBundle().AddSoundFromPath("sounds/music.wav"))
-}
-
 // NewCollisionPlayerTransfer creates an collidable entity/shape that will transfer the player
 // to the targeted pos and scene upon touching it
 func NewCollisionPlayerTransfer(
@@ -0,0 +1,59 @@
+package scenes
+
+import (
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/warehouse"
+
+	"github.com/TheBitDrifter/bappa/blueprint/ldtk"
+)
+
+var entityRegistry = ldtk.NewLDtkEntityRegistry()
+
+// Local scene object makes it easier to organize
+type Scene struct {
+	Name          string
+	Plan          blueprint.Plan
+	Width, Height int
+}
+
+// Registering custom LDTK entities
+func init() {
+	// Player start position handler
+	entityRegistry.Register("PlayerStart", func(entity *ldtk.LDtkEntityInstance, sto warehouse.Storage) error {
+		// Create the players at the position defined in LDtk
+		return NewPlayer(float64(entity.Position[0]), float64(entity.Position[1]), sto, 2)
+	})
+
+	// Scene transition trigger handler
+	entityRegistry.Register("SceneTransfer", func(entity *ldtk.LDtkEntityInstance, sto warehouse.Storage) error {
+		// Extract properties from LDtk entity
+		targetScene := entity.StringFieldOr("targetScene", SCENE_TWO_NAME) // Default to scene two if not specified
+		targetX := entity.FloatFieldOr("targetX", 225.0)
+		targetY := entity.FloatFieldOr("targetY", 15.0)
+
+		width := entity.FloatFieldOr("width", 60)
+		height := entity.FloatFieldOr("height", 10)
+
+		// Create the transfer trigger (scene change)
+		return NewCollisionPlayerTransfer(
+			sto,
+			float64(entity.Position[0]),
+			float64(entity.Position[1]),
+			width,
+			height,
+			targetX,
+			targetY,
+			targetScene,
+		)
+	})
+
+	// Tree
+	entityRegistry.Register("Tree", func(entity *ldtk.LDtkEntityInstance, sto warehouse.Storage) error {
+		return NewTreeProp(sto, float64(entity.Position[0]), float64(entity.Position[1]))
+	})
+
+	// Moveable statue
+	entityRegistry.Register("Statue", func(entity *ldtk.LDtkEntityInstance, sto warehouse.Storage) error {
+		return NewMoveableStatueProp(sto, float64(entity.Position[0]), float64(entity.Position[1]))
+	})
+}
@@ -0,0 +1,36 @@
+package scenes
+
+import (
+	"github.com/TheBitDrifter/bappa/blueprint"
+	"github.com/TheBitDrifter/bappa/blueprint/vector"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/ldtk"
+)
+
+const SCENE_TWO_NAME = "Scene2"
+
+var SceneTwo = Scene{
+	Name:   SCENE_TWO_NAME,
+	Plan:   sceneTwoPlan,
+	Width:  ldtk.DATA.WidthFor(SCENE_TWO_NAME),
+	Height: ldtk.DATA.HeightFor(SCENE_TWO_NAME),
+}
+
+func sceneTwoPlan(width, height int, sto warehouse.Storage) error {
+	// Background
+	err := blueprint.CreateStillBackground(sto, "images/backgrounds/scene_two.png", vector.Two{X: 140, Y: 0})
+	if err != nil {
+		return err
+	}
+
+	// Load the bounds
+	// Pass the terrain archetypes in order of int grid layer they map to
+	blockArchetype, _ := sto.NewOrExistingArchetype(BlockTerrainComposition...)
+	err = ldtk.DATA.LoadIntGrid(SCENE_TWO_NAME, sto, blockArchetype)
+	if err != nil {
+		return err
+	}
+
+	// Load the scene transfers
+	return ldtk.DATA.LoadEntities(SCENE_TWO_NAME, sto, entityRegistry)
+}
@@ -0,0 +1,6 @@
+package components
+
+import "github.com/TheBitDrifter/bappa/warehouse"
+
+// MusicTag marks the entity playing the background music
+var MusicTag = warehouse.FactoryNewComponent[struct{}]()
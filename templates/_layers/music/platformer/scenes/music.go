@@ -0,0 +1,22 @@
+package scenes
+
+import (
+	"github.com/TheBitDrifter/bappa/blueprint/client"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/bappacreate/templates/common/components"
+	"github.com/TheBitDrifter/bappacreate/templates/common/sounds"
+)
+
+var MusicComposition = []warehouse.Component{
+	client.Components.SoundBundle,
+	components.MusicTag,
+}
+
+// NewJazzMusic adds a Jazz music entity
+func NewJazzMusic(sto warehouse.Storage) error {
+	musicArche, err := sto.NewOrExistingArchetype(MusicComposition...)
+	if err != nil {
+		return err
+	}
+	return musicArche.Generate(1, client.NewSoundBundle().AddSoundFromConfig(sounds.Music))
+}
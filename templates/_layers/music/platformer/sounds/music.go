@@ -0,0 +1,8 @@
+package sounds
+
+import "github.com/TheBitDrifter/bappa/blueprint/client"
+
+var Music = client.SoundConfig{
+	Path:             "sounds/music.wav",
+	AudioPlayerCount: 1,
+}
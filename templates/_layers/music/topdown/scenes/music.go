@@ -0,0 +1,22 @@
+package scenes
+
+import (
+	"github.com/TheBitDrifter/bappa/blueprint/client"
+	"github.com/TheBitDrifter/bappa/warehouse"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/components"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/sounds"
+)
+
+var MusicComposition = []warehouse.Component{
+	client.Components.SoundBundle,
+	components.MusicTag,
+}
+
+// NewFantasyMusic creates a simple music entity with the default fantasy music wav
+func NewFantasyMusic(sto warehouse.Storage) error {
+	musicArche, err := sto.NewOrExistingArchetype(MusicComposition...)
+	if err != nil {
+		return err
+	}
+	return musicArche.Generate(1, client.NewSoundBundle().AddSoundFromConfig(sounds.Music))
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/TheBitDrifter/bappa/coldbrew"
 	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
 	"github.com/TheBitDrifter/bappa/warehouse"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/components"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/components"
 )
 
 type CollisionPlayerTransferSystem struct{}
@@ -4,8 +4,8 @@ import (
 	"github.com/TheBitDrifter/bappa/blueprint"
 	"github.com/TheBitDrifter/bappa/blueprint/client"
 	"github.com/TheBitDrifter/bappa/coldbrew"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/animations"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/components"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/animations"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/components"
 )
 
 type PlayerAnimationSystem struct{}
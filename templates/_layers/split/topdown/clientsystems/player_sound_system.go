@@ -4,8 +4,8 @@ import (
 	"github.com/TheBitDrifter/bappa/blueprint/client"
 	"github.com/TheBitDrifter/bappa/coldbrew"
 	"github.com/TheBitDrifter/bappa/warehouse"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/components"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/sounds"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/components"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/sounds"
 )
 
 type PlayerSoundSystem struct{}
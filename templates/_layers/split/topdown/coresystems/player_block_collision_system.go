@@ -5,7 +5,7 @@ import (
 	"github.com/TheBitDrifter/bappa/tteokbokki/motion"
 	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
 	"github.com/TheBitDrifter/bappa/warehouse"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/components"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/components"
 )
 
 type PlayerBlockCollisionSystem struct{}
@@ -6,8 +6,8 @@ import (
 	"github.com/TheBitDrifter/bappa/blueprint"
 	"github.com/TheBitDrifter/bappa/blueprint/input"
 	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/actions"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/components"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/actions"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/components"
 )
 
 type PlayerMovementSystem struct{}
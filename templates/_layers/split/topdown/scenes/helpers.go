@@ -7,9 +7,9 @@ import (
 	"github.com/TheBitDrifter/bappa/tteokbokki/motion"
 	"github.com/TheBitDrifter/bappa/tteokbokki/spatial"
 	"github.com/TheBitDrifter/bappa/warehouse"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/animations"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/components"
-	"github.com/TheBitDrifter/bappacreate/templates/topdown-split/sounds"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/animations"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/components"
+	"github.com/TheBitDrifter/bappacreate/templates/topdown/sounds"
 )
 
 // NewPlayer creates a player entity
@@ -124,12 +124,3 @@ func NewCollisionPlayerTransfer(
 		},
 	)
 }
-
-// NewFantasyMusic creates a simple music entity with the default fantasy music wav
-func NewFantasyMusic(sto warehouse.Storage) error {
-	musicArche, err := sto.NewOrExistingArchetype(MusicComposition...)
-	if err != nil {
-		return err
-	}
-	return musicArche.Generate(1, client.NewSoundBundle().AddSoundFromConfig(sounds.Music))
-}
@@ -7,8 +7,3 @@ var Run = client.SoundConfig{
 	// We could increase the count for two players, but it sounds too clustered in my opinion
 	AudioPlayerCount: 1,
 }
-
-var Music = client.SoundConfig{
-	Path:             "sounds/fantasy_music.wav",
-	AudioPlayerCount: 1,
-}
@@ -11,5 +11,4 @@ import "github.com/TheBitDrifter/bappa/warehouse"
 var (
 	BlockTerrainTag = warehouse.FactoryNewComponent[struct{}]()
 	PlatformTag     = warehouse.FactoryNewComponent[struct{}]()
-	MusicTag        = warehouse.FactoryNewComponent[struct{}]()
 )
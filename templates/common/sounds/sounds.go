@@ -16,8 +16,3 @@ var Land = client.SoundConfig{
 	Path:             "sounds/land.wav",
 	AudioPlayerCount: 2, // matches max player count
 }
-
-var Music = client.SoundConfig{
-	Path:             "sounds/music.wav",
-	AudioPlayerCount: 1,
-}
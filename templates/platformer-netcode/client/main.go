@@ -36,7 +36,7 @@ func main() {
 	client.SetLocalAssetPath("../sharedclient/assets/")
 
 	// Client Settings
-	client.SetTitle("Platformer LDTK Template (Networked)")
+	client.SetTitle(sharedclient.TITLE + " (Networked)")
 	client.SetResizable(true)
 	client.SetMinimumLoadTime(30)
 
@@ -28,7 +28,7 @@ func main() {
 
 	client.SetLocalAssetPath("../sharedclient/assets/")
 
-	client.SetTitle("Platformer LDTK Template Networked-standalone")
+	client.SetTitle(sharedclient.TITLE + " (Standalone)")
 	client.SetResizable(true)
 	client.SetMinimumLoadTime(30)
 
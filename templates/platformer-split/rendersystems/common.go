@@ -1,7 +0,0 @@
-package rendersystems
-
-import "github.com/TheBitDrifter/bappa/coldbrew"
-
-var DefaultRenderSystems = []coldbrew.RenderSystem{
-	PlayerCameraPriorityRenderer{},
-}
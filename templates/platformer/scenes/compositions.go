@@ -42,11 +42,6 @@ var PlatformComposition = []warehouse.Component{
 	motion.Components.Dynamics,
 }
 
-var MusicComposition = []warehouse.Component{
-	client.Components.SoundBundle,
-	components.MusicTag,
-}
-
 var CollisionPlayerTransferComposition = []warehouse.Component{
 	spatial.Components.Position,
 	spatial.Components.Shape,
@@ -1,8 +1,8 @@
+// Package sounds holds the game's sound configs. Add the wav files to
+// assets/sounds and declare a config for each, for example:
+//
+//	var Jump = client.SoundConfig{
+//		Path:             "jump.wav",
+//		AudioPlayerCount: 1,
+//	}
 package sounds
-
-import "github.com/TheBitDrifter/bappa/blueprint/client"
-
-var ExampleSound = client.SoundConfig{
-	Path:             "example.wav", // file does not exist
-	AudioPlayerCount: 1,
-}
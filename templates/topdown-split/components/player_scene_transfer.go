@@ -1,7 +0,0 @@
-package components
-
-// A component for storing transfer data
-type PlayerSceneTransfer struct {
-	Dest string
-	X, Y float64
-}
@@ -6,5 +6,4 @@ import "github.com/TheBitDrifter/bappa/warehouse"
 // composition alone isn't enough.
 var (
 	BlockTerrainTag = warehouse.FactoryNewComponent[struct{}]()
-	MusicTag        = warehouse.FactoryNewComponent[struct{}]()
 )
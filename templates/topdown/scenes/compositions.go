@@ -47,8 +47,3 @@ var CollisionPlayerTransferComposition = []warehouse.Component{
 	spatial.Components.Shape,
 	components.PlayerSceneTransferComponent,
 }
-
-var MusicComposition = []warehouse.Component{
-	client.Components.SoundBundle,
-	components.MusicTag,
-}
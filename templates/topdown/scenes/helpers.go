@@ -116,12 +116,3 @@ func NewCollisionPlayerTransfer(
 		},
 	)
 }
-
-// NewFantasyMusic creates a simple music entity with the default fantasy music wav
-func NewFantasyMusic(sto warehouse.Storage) error {
-	musicArche, err := sto.NewOrExistingArchetype(MusicComposition...)
-	if err != nil {
-		return err
-	}
-	return musicArche.Generate(1, client.NewSoundBundle().AddSoundFromConfig(sounds.Music))
-}
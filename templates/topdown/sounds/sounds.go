@@ -6,8 +6,3 @@ var Run = client.SoundConfig{
 	Path:             "sounds/run.wav",
 	AudioPlayerCount: 1,
 }
-
-var Music = client.SoundConfig{
-	Path:             "sounds/fantasy_music.wav",
-	AudioPlayerCount: 1,
-}
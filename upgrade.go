@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+func printUpgradeUsage() {
+	fmt.Println("Usage: bappacreate upgrade [flags]")
+	fmt.Println()
+	fmt.Println("Brings changes of the template into the project in the current directory,")
+	fmt.Println("using the .bappacreate.json written when the project was generated.")
+	fmt.Println()
+	fmt.Println("Files you haven't changed are replaced by their new version. Changes of the")
+	fmt.Println("template are merged into files you changed too. Where you and the template")
+	fmt.Println("changed the same lines, the file gets conflict markers, or with --conflict")
+	fmt.Println("orig the template version with yours saved as <file>.orig.")
+}
+
+// How `upgrade` writes files both the user and the template changed
+const (
+	conflictMarkers = "markers" // the merged file with both versions of the conflicting lines in conflict markers
+	conflictOrig    = "orig"    // the template's file, the user's saved next to it as .orig
+)
+
+// upgradeAction is what `upgrade` does with one file
+type upgradeAction struct {
+	Path    string
+	Action  string // one of the upgrade* constants
+	content []byte // content written to the file, nil to remove it
+	orig    []byte // content written to <path>.orig
+}
+
+// Actions of `upgrade`, as printed
+const (
+	upgradeAdd      = "added"
+	upgradeUpdate   = "updated"
+	upgradeMerge    = "merged" // changed by both the user and the template, without conflicts
+	upgradeRemove   = "removed"
+	upgradeConflict = "conflict"
+	upgradeKeep     = "kept"    // removed from the template, but changed by the user
+	upgradeDeleted  = "deleted" // changed in the template, but deleted by the user
+)
+
+func runUpgrade(args []string) error {
+	flags := newFlagSet("upgrade", printUpgradeUsage)
+	opts := templateOptions{}
+	addTemplateFlags(flags, &opts)
+	conflict := flags.String("conflict", conflictMarkers, "how to write files both you and the template changed: markers or orig")
+	dryRun := flags.Bool("dry-run", false, "print what would change without writing anything")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usageErrorf(flags, "unexpected arguments: %s", strings.Join(positional, " "))
+	}
+	if *conflict != conflictMarkers && *conflict != conflictOrig {
+		return usageErrorf(flags, "--conflict must be markers or orig")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	lock, projectDir, err := findLockfile(wd)
+	if err != nil {
+		return err
+	}
+	if opts.Dir == "" && opts.Repo == "" {
+		opts = lock.templateOptions()
+	}
+	return upgradeProject(projectDir, lock, opts, *conflict, *dryRun)
+}
+
+// upgradeProject regenerates the project's template and merges it into projectDir
+func upgradeProject(projectDir string, lock *lockfile, opts templateOptions, conflict string, dryRun bool) error {
+	templates, cleanup, err := loadTemplates(opts)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	template, err := selectTemplate(lock.Template, lock.Genre, lock.With, templates)
+	if err != nil {
+		return err
+	}
+
+	names, err := resolveProjectNames(lock.Project, lock.Module)
+	if err != nil {
+		return err
+	}
+	names.Author = lock.Author
+	if lock.Title != "" {
+		names.Title = lock.Title
+	}
+	plan, err := buildPlan(template.Manifest, template.Source, names, dependencyOptions{})
+	if err != nil {
+		return fmt.Errorf("planning project: %v", err)
+	}
+	if lock.Resolution != nil {
+		plan.Resolution = *lock.Resolution
+	}
+
+	newLock := *lock
+	newLock.Version = buildVersion()
+	if err := newLock.setTemplateOptions(opts); err != nil {
+		return err
+	}
+	newLock.Files = map[string]string{}
+	contents := map[string][]byte{}
+	label := fmt.Sprintf("%s template (bappacreate %s)", template.Manifest.Name, newLock.Version)
+
+	var actions []upgradeAction
+	var errs []error
+	for _, file := range plan.Files {
+		if !tracksFile(file) {
+			continue
+		}
+		theirs, err := renderFile(plan, file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		newLock.Files[file.Path], contents[file.Path] = contentHash(theirs), theirs
+
+		baseHash, base := lock.base(projectDir, file.Path)
+		action, err := upgradeFile(projectDir, file, baseHash, base, theirs, conflict, label)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if action != nil {
+			actions = append(actions, *action)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Files the template no longer has
+	var removed []string
+	for name := range lock.Files {
+		if _, ok := newLock.Files[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		ours, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(name)))
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return err
+		case contentHash(ours) == lock.Files[name]:
+			actions = append(actions, upgradeAction{Path: name, Action: upgradeRemove})
+		default:
+			actions = append(actions, upgradeAction{Path: name, Action: upgradeKeep})
+		}
+	}
+
+	printUpgrade(actions, lock, &newLock, dryRun)
+	if dryRun {
+		return nil
+	}
+	for _, action := range actions {
+		if err := applyUpgrade(projectDir, action); err != nil {
+			return err
+		}
+	}
+	if err := newLock.write(projectDir); err != nil {
+		return err
+	}
+	return writeBase(projectDir, plan, contents)
+}
+
+// upgradeFile works out what upgrading one file does. baseHash is the hash of the
+// content the template generated before, "" for files new in the template, and
+// base that content, nil when the lockfile has no copy of it.
+func upgradeFile(projectDir string, file plannedFile, baseHash string, base, theirs []byte, conflict, label string) (*upgradeAction, error) {
+	ours, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(file.Path)))
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	switch {
+	case exists && bytes.Equal(ours, theirs):
+		// Already up to date
+		return nil, nil
+	case baseHash != "" && contentHash(theirs) == baseHash:
+		// The template didn't change, the user's version stays
+		return nil, nil
+	case !exists && baseHash == "":
+		return &upgradeAction{Path: file.Path, Action: upgradeAdd, content: theirs}, nil
+	case !exists:
+		return &upgradeAction{Path: file.Path, Action: upgradeDeleted}, nil
+	case contentHash(ours) == baseHash:
+		return &upgradeAction{Path: file.Path, Action: upgradeUpdate, content: theirs}, nil
+	case baseHash == "":
+		// The user added a file the template now has too
+		base = []byte{}
+	}
+
+	// Both sides changed the file
+	if base != nil && !file.Binary {
+		merged, conflicts := mergeLines(base, ours, theirs, label)
+		switch {
+		case conflicts == 0:
+			return &upgradeAction{Path: file.Path, Action: upgradeMerge, content: merged}, nil
+		case conflict == conflictMarkers:
+			return &upgradeAction{Path: file.Path, Action: upgradeConflict, content: merged}, nil
+		}
+	}
+	return &upgradeAction{Path: file.Path, Action: upgradeConflict, content: theirs, orig: ours}, nil
+}
+
+func printUpgrade(actions []upgradeAction, lock, newLock *lockfile, dryRun bool) {
+	fmt.Printf("Upgrading %s template files from bappacreate %s to %s\n", newLock.Template, lock.Version, newLock.Version)
+	if len(actions) == 0 {
+		fmt.Println("\nThe project is up to date.")
+		return
+	}
+
+	fmt.Println()
+	conflicts := 0
+	for _, action := range actions {
+		note := ""
+		switch action.Action {
+		case upgradeConflict:
+			conflicts++
+			note = " (conflict markers added)"
+			if action.orig != nil {
+				note = " (your version saved as " + action.Path + ".orig)"
+			}
+		case upgradeMerge:
+			note = " (merged with your changes)"
+		case upgradeKeep:
+			note = " (removed from the template, but you changed it)"
+		case upgradeDeleted:
+			note = " (changed in the template, but you deleted it)"
+		}
+		fmt.Printf("  %-9s %s%s\n", action.Action, action.Path, note)
+	}
+
+	if dryRun {
+		fmt.Println("\nDry run, nothing was written.")
+		return
+	}
+	if conflicts > 0 {
+		fmt.Printf("\n%d file(s) need merging by hand, look for <<<<<<< or .orig files.\n", conflicts)
+	}
+}
+
+// applyUpgrade writes the result of one upgrade action
+func applyUpgrade(projectDir string, action upgradeAction) error {
+	target := filepath.Join(projectDir, filepath.FromSlash(action.Path))
+	switch action.Action {
+	case upgradeRemove:
+		return os.Remove(target)
+	case upgradeKeep, upgradeDeleted:
+		return nil
+	}
+
+	if action.orig != nil {
+		if err := os.WriteFile(target+".orig", action.orig, 0644); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, action.content, 0644)
+}
+
+// Files with more lines than this are merged as a whole, the line diff is quadratic
+const maxMergeLines = 4000
+
+// mergeLines merges the changes ours and theirs made to base, line by line. Lines
+// only one side changed get that side's version. Where both sides changed the
+// same lines differently, both versions are written in conflict markers. It
+// returns the merged content and the number of conflicts.
+func mergeLines(base, ours, theirs []byte, label string) ([]byte, int) {
+	o, a, b := splitLines(base), splitLines(ours), splitLines(theirs)
+
+	var out bytes.Buffer
+	conflicts := 0
+	// merge writes a run of lines where at least one side differs from base
+	merge := func(o, a, b []string) {
+		switch {
+		case slices.Equal(a, o):
+			out.WriteString(strings.Join(b, ""))
+		case slices.Equal(b, o), slices.Equal(a, b):
+			out.WriteString(strings.Join(a, ""))
+		default:
+			// Lines the sides share at the start and end of the run aren't in conflict
+			start := 0
+			for start < len(a) && start < len(b) && a[start] == b[start] {
+				start++
+			}
+			end := 0
+			for end < len(a)-start && end < len(b)-start && a[len(a)-1-end] == b[len(b)-1-end] {
+				end++
+			}
+			conflicts++
+			out.WriteString(strings.Join(a[:start], ""))
+			out.WriteString("<<<<<<< yours\n")
+			writeLines(&out, a[start:len(a)-end])
+			out.WriteString("=======\n")
+			writeLines(&out, b[start:len(b)-end])
+			out.WriteString(">>>>>>> " + label + "\n")
+			out.WriteString(strings.Join(a[len(a)-end:], ""))
+		}
+	}
+	if len(o) > maxMergeLines || len(a) > maxMergeLines || len(b) > maxMergeLines {
+		merge(o, a, b)
+		return out.Bytes(), conflicts
+	}
+
+	// Base lines both sides kept split the files into runs merged one by one
+	inOurs, inTheirs := matchLines(o, a), matchLines(o, b)
+	i, j, k := 0, 0, 0 // next line of base, ours and theirs
+	for {
+		next := i
+		for next < len(o) && (inOurs[next] < 0 || inTheirs[next] < 0) {
+			next++
+		}
+		endA, endB := len(a), len(b)
+		if next < len(o) {
+			endA, endB = inOurs[next], inTheirs[next]
+		}
+		merge(o[i:next], a[j:endA], b[k:endB])
+		if next == len(o) {
+			return out.Bytes(), conflicts
+		}
+		out.WriteString(o[next])
+		i, j, k = next+1, endA+1, endB+1
+	}
+}
+
+// matchLines returns, for every line of a, the index of the same line of b in a
+// longest common subsequence of a and b, or -1 when the line isn't in it
+func matchLines(a, b []string) []int {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	matches := make([]int, len(a))
+	for i := range matches {
+		matches[i] = -1
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			matches[i] = j
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+// splitLines splits content into lines that keep their line endings, so joining
+// them gives back content
+func splitLines(content []byte) []string {
+	var lines []string
+	for len(content) > 0 {
+		end := bytes.IndexByte(content, '\n') + 1
+		if end == 0 {
+			end = len(content)
+		}
+		lines = append(lines, string(content[:end]))
+		content = content[end:]
+	}
+	return lines
+}
+
+// writeLines writes lines, ending the last one with a newline if it has none
+func writeLines(out *bytes.Buffer, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			out.WriteByte('\n')
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeLines(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflicts          int
+	}{
+		{
+			name:   "changes to different lines",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "a\nB\nc\nd\ne\n",
+			theirs: "a\nb\nc\nD\ne\n",
+			want:   "a\nB\nc\nD\ne\n",
+		},
+		{
+			name:   "additions at both ends",
+			base:   "package scenes\n\nfunc f() {}\n",
+			ours:   "// Package scenes\npackage scenes\n\nfunc f() {}\n",
+			theirs: "package scenes\n\nfunc f() {}\n\nfunc g() {}\n",
+			want:   "// Package scenes\npackage scenes\n\nfunc f() {}\n\nfunc g() {}\n",
+		},
+		{
+			name:   "the same change on both sides",
+			base:   "a\nb\nc\n",
+			ours:   "a\nx\nc\n",
+			theirs: "a\nx\nc\n",
+			want:   "a\nx\nc\n",
+		},
+		{
+			name:   "removal and unrelated change",
+			base:   "a\nb\nc\nd\n",
+			ours:   "a\nc\nd\n",
+			theirs: "a\nb\nc\nD\n",
+			want:   "a\nc\nD\n",
+		},
+		{
+			name:      "both change the same line",
+			base:      "a\nb\nc\n",
+			ours:      "a\nyours\nc\n",
+			theirs:    "a\ntheirs\nc\n",
+			want:      "a\n<<<<<<< yours\nyours\n=======\ntheirs\n>>>>>>> template\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "a removed line changed by the other side",
+			base:      "a\nb\nc\n",
+			ours:      "a\nc\n",
+			theirs:    "a\nB\nc\n",
+			want:      "a\n<<<<<<< yours\n=======\nB\n>>>>>>> template\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "both add different lines at the same place",
+			base:      "a\nb\n",
+			ours:      "a\nyours\nb\n",
+			theirs:    "a\ntheirs\nb\n",
+			want:      "a\n<<<<<<< yours\nyours\n=======\ntheirs\n>>>>>>> template\nb\n",
+			conflicts: 1,
+		},
+		{
+			name:      "one conflict among clean changes",
+			base:      "1\n2\n3\n4\n5\n6\n7\n",
+			ours:      "one\n2\n3\nfour\n5\n6\n7\n",
+			theirs:    "1\n2\n3\nFOUR\n5\n6\nseven\n",
+			want:      "one\n2\n3\n<<<<<<< yours\nfour\n=======\nFOUR\n>>>>>>> template\n5\n6\nseven\n",
+			conflicts: 1,
+		},
+		{
+			name:   "no newline at the end",
+			base:   "a\nb\nc",
+			ours:   "A\nb\nc",
+			theirs: "a\nb\nc\nd",
+			want:   "A\nb\nc\nd",
+		},
+		{
+			name:      "only the differing lines of a conflict are marked",
+			base:      "a\nb\nc\n",
+			ours:      "a\nx\ny\nz\nc\n",
+			theirs:    "a\nx\nY\nz\nc\n",
+			want:      "a\nx\n<<<<<<< yours\ny\n=======\nY\n>>>>>>> template\nz\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "files added on both sides",
+			base:      "",
+			ours:      "same\nyours\n",
+			theirs:    "same\ntheirs\n",
+			want:      "same\n<<<<<<< yours\nyours\n=======\ntheirs\n>>>>>>> template\n",
+			conflicts: 1,
+		},
+	}
+	for _, tt := range tests {
+		got, conflicts := mergeLines([]byte(tt.base), []byte(tt.ours), []byte(tt.theirs), "template")
+		if string(got) != tt.want || conflicts != tt.conflicts {
+			t.Errorf("%s: got %d conflict(s)\n%s\nwant %d\n%s", tt.name, conflicts, got, tt.conflicts, tt.want)
+		}
+	}
+}
+
+func TestUpgradeFile(t *testing.T) {
+	const (
+		base   = "a\nb\nc\nd\n"
+		theirs = "a\nb\nc\nD\n" // the template changed the last line
+	)
+	tests := []struct {
+		name     string
+		ours     string // the user's file, "" when it doesn't exist
+		baseHash string // "" for files new in the template
+		base     string // recorded content, "" when not recorded
+		theirs   string
+		binary   bool
+		conflict string
+		want     *upgradeAction
+	}{
+		{name: "up to date", ours: theirs, baseHash: contentHash([]byte(base)), base: base, theirs: theirs},
+		{name: "template unchanged", ours: "mine\n", baseHash: contentHash([]byte(base)), base: base, theirs: base},
+		{name: "new in the template", theirs: theirs, want: &upgradeAction{Action: upgradeAdd, content: []byte(theirs)}},
+		{name: "deleted by the user", baseHash: contentHash([]byte(base)), base: base, theirs: theirs, want: &upgradeAction{Action: upgradeDeleted}},
+		{name: "unchanged by the user", ours: base, baseHash: contentHash([]byte(base)), base: base, theirs: theirs, want: &upgradeAction{Action: upgradeUpdate, content: []byte(theirs)}},
+		{
+			name: "both changed other lines", ours: "A\nb\nc\nd\n", baseHash: contentHash([]byte(base)), base: base, theirs: theirs,
+			want: &upgradeAction{Action: upgradeMerge, content: []byte("A\nb\nc\nD\n")},
+		},
+		{
+			name: "both changed other lines with --conflict orig", ours: "A\nb\nc\nd\n", baseHash: contentHash([]byte(base)), base: base, theirs: theirs, conflict: conflictOrig,
+			want: &upgradeAction{Action: upgradeMerge, content: []byte("A\nb\nc\nD\n")},
+		},
+		{
+			name: "both changed the same line", ours: "a\nb\nc\nmine\n", baseHash: contentHash([]byte(base)), base: base, theirs: theirs,
+			want: &upgradeAction{Action: upgradeConflict, content: []byte("a\nb\nc\n<<<<<<< yours\nmine\n=======\nD\n>>>>>>> template\n")},
+		},
+		{
+			name: "both changed the same line with --conflict orig", ours: "a\nb\nc\nmine\n", baseHash: contentHash([]byte(base)), base: base, theirs: theirs, conflict: conflictOrig,
+			want: &upgradeAction{Action: upgradeConflict, content: []byte(theirs), orig: []byte("a\nb\nc\nmine\n")},
+		},
+		{
+			name: "content not recorded", ours: "A\nb\nc\nd\n", baseHash: contentHash([]byte(base)), theirs: theirs,
+			want: &upgradeAction{Action: upgradeConflict, content: []byte(theirs), orig: []byte("A\nb\nc\nd\n")},
+		},
+		{
+			name: "binary", ours: "mine", baseHash: contentHash([]byte("base")), theirs: "theirs", binary: true,
+			want: &upgradeAction{Action: upgradeConflict, content: []byte("theirs"), orig: []byte("mine")},
+		},
+		{
+			name: "added by both", ours: "mine\n", theirs: theirs,
+			want: &upgradeAction{Action: upgradeConflict, content: []byte("<<<<<<< yours\nmine\n=======\na\nb\nc\nD\n>>>>>>> template\n")},
+		},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		file := plannedFile{Path: "scenes/helpers.go", Binary: tt.binary}
+		if tt.ours != "" {
+			name := filepath.Join(dir, "scenes", "helpers.go")
+			if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(name, []byte(tt.ours), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var recorded []byte
+		if tt.base != "" {
+			recorded = []byte(tt.base)
+		}
+		conflict := tt.conflict
+		if conflict == "" {
+			conflict = conflictMarkers
+		}
+
+		got, err := upgradeFile(dir, file, tt.baseHash, recorded, []byte(tt.theirs), conflict, "template")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.want != nil {
+			tt.want.Path = file.Path
+		}
+		if describeAction(got) != describeAction(tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.name, describeAction(got), describeAction(tt.want))
+		}
+	}
+}
+
+// TestUpgradeProject upgrades a project whose lockfile says the template didn't
+// have a line of scenes/helpers.go yet, after the user changed another line of it
+func TestUpgradeProject(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	opts := projectOptions{Name: "me/game", Template: "topdown", NoHooks: true, Deps: dependencyOptions{Offline: true}}
+	if err := createProject(opts); err != nil {
+		t.Fatal(err)
+	}
+	lock, dir, err := findLockfile("game")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const name = "scenes/helpers.go"
+	baseFile := filepath.Join(dir, baseDirName, "base", name)
+	_, base := lock.base(dir, name)
+	template := string(base)
+	added := "// NewPlayer creates a player entity\n"
+	if !strings.Contains(template, added) {
+		t.Fatalf("%s has no line %q:\n%s", name, added, template)
+	}
+	older := strings.Replace(template, added, "", 1)
+	lock.Files[name] = contentHash([]byte(older))
+	if err := lock.write(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(baseFile, []byte(older), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ours := strings.Replace(older, "package scenes\n", "// Package scenes creates the entities of the game\npackage scenes\n", 1)
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(ours), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := upgradeProject(dir, lock, templateOptions{}, conflictMarkers, false); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(template, "package scenes\n", "// Package scenes creates the entities of the game\npackage scenes\n", 1)
+	if string(merged) != want {
+		t.Errorf("merged %s:\n%s\nwant\n%s", name, merged, want)
+	}
+
+	// The next upgrade merges against the template's current version
+	upgraded, _, err := findLockfile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, base := upgraded.base(dir, name); string(base) != template || upgraded.Files[name] != contentHash([]byte(template)) {
+		t.Errorf("the project doesn't keep the current template version of %s", name)
+	}
+}
+
+// TestLockfileBase checks that the lockfile only records hashes, and that the
+// copies upgrades merge against are kept out of git and only used while they
+// are the recorded version
+func TestLockfileBase(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	opts := projectOptions{Name: "me/game", Template: "topdown", NoHooks: true, Deps: dependencyOptions{Offline: true}}
+	if err := createProject(opts); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join("game", lockfileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "package scenes") || len(data) > 16<<10 {
+		t.Errorf("%s holds file contents, it is %d bytes", lockfileName, len(data))
+	}
+	ignore, err := os.ReadFile(filepath.Join("game", baseDirName, ".gitignore"))
+	if err != nil || string(ignore) != "*\n" {
+		t.Errorf("%s doesn't ignore itself: %q, %v", baseDirName, ignore, err)
+	}
+
+	lock, dir, err := findLockfile("game")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"main.go", "assets/sounds/run.wav"} {
+		if _, ok := lock.Files[name]; !ok {
+			t.Errorf("%s isn't recorded", name)
+		}
+	}
+	if _, base := lock.base(dir, "main.go"); base == nil {
+		t.Error("main.go has no base copy")
+	}
+	if _, base := lock.base(dir, "assets/sounds/run.wav"); base != nil {
+		t.Error("a binary file has a base copy")
+	}
+
+	// A copy that isn't the recorded version isn't merged against
+	if err := os.WriteFile(filepath.Join(dir, baseDirName, "base", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if hash, base := lock.base(dir, "main.go"); hash != lock.Files["main.go"] || base != nil {
+		t.Errorf("base(main.go) = %s, %q, want the recorded hash without content", hash, base)
+	}
+}
+
+// TestUpgradeTemplateDir checks that a relative --template-dir is recorded as an
+// absolute path by new and upgrade alike
+func TestUpgradeTemplateDir(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	writeTree(t, "templates", map[string]string{"template.json": testManifestJSON("arena"), "main.go": "package main\n\nfunc main() {}\n"})
+	abs, err := filepath.Abs("templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := templateOptions{Dir: "templates"}
+	opts := projectOptions{Name: "me/game", Template: "arena", Templates: templates, NoHooks: true, Deps: dependencyOptions{Offline: true}}
+	if err := createProject(opts); err != nil {
+		t.Fatal(err)
+	}
+	lock, dir, err := findLockfile("game")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.TemplateDir != abs {
+		t.Errorf("new recorded the template directory %q, want %q", lock.TemplateDir, abs)
+	}
+
+	lock.TemplateDir = ""
+	if err := upgradeProject(dir, lock, templates, conflictMarkers, false); err != nil {
+		t.Fatal(err)
+	}
+	if lock, _, err = findLockfile(dir); err != nil {
+		t.Fatal(err)
+	}
+	if lock.TemplateDir != abs {
+		t.Errorf("upgrade recorded the template directory %q, want %q", lock.TemplateDir, abs)
+	}
+}
+
+// describeAction prints action, nil meaning the file is left alone
+func describeAction(action *upgradeAction) string {
+	if action == nil {
+		return "nothing"
+	}
+	var b strings.Builder
+	b.WriteString(action.Action + " " + action.Path)
+	if action.content != nil {
+		b.WriteString(" with\n" + string(action.content))
+	}
+	if action.orig != nil {
+		b.WriteString(" and .orig\n" + string(action.orig))
+	}
+	return b.String()
+}
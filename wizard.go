@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// isInteractive reports whether bappacreate runs in a terminal that can answer prompts
+func isInteractive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// prompter asks questions in the terminal
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter() *prompter {
+	return &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+}
+
+// readLine reads one answer, without surrounding spaces
+func (p *prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if errors.Is(err, io.EOF) && line == "" {
+		fmt.Fprintln(p.out)
+		return "", errors.New("cancelled, no project was created")
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// ask asks question until check accepts the answer. An empty answer is def.
+func (p *prompter) ask(question, def string, check func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+		answer, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = def
+		}
+		if err := check(answer); err != nil {
+			fmt.Fprintf(p.out, "  %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// confirm asks a yes or no question
+func (p *prompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, hint)
+		answer, err := p.readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "  Please answer y or n")
+	}
+}
+
+// choose asks for one of the templates, by number or name, and returns its name
+func (p *prompter) choose(question string, templates []availableTemplate, def string) (string, error) {
+	fmt.Fprintln(p.out, question)
+	for i, template := range templates {
+		fmt.Fprintf(p.out, "  %d. %-12s %s\n", i+1, template.Manifest.Name, template.Manifest.Description)
+	}
+	var chosen string
+	_, err := p.ask("Choose", def, func(answer string) error {
+		for i, template := range templates {
+			if answer == template.Manifest.Name || answer == strconv.Itoa(i+1) {
+				chosen = template.Manifest.Name
+				return nil
+			}
+		}
+		return fmt.Errorf("enter a number from 1 to %d, or a name", len(templates))
+	})
+	return chosen, err
+}
+
+// runWizard asks for the settings of a new project that opts doesn't set, shows
+// the plan and creates the project once it's confirmed
+func runWizard(opts projectOptions) error {
+	templates, cleanup, err := loadTemplates(opts.Templates)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	layers, err := loadLayers()
+	if err != nil {
+		return err
+	}
+
+	p := newPrompter()
+	fmt.Println("Create a new Bappa game project. Press Enter to take the [default].")
+	fmt.Println()
+
+	var names projectNames
+	opts.Name, err = p.ask("Module path, e.g. you/my-game or github.com/you/my-game", "", func(answer string) error {
+		if names, err = resolveProjectNames(answer, ""); err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return nil
+		}
+		return checkProjectDir(names.Dir, opts.writeMode())
+	})
+	if err != nil {
+		return err
+	}
+
+	// --template and --genre already pick the template
+	if opts.Template == "" && opts.Genre == "" {
+		if err := askTemplate(p, &opts, templates, layers); err != nil {
+			return err
+		}
+	}
+	template, err := selectTemplate(opts.Template, opts.Genre, opts.With, templates)
+	if err != nil {
+		return err
+	}
+	if err := askAllowHooks(p, &opts, template); err != nil {
+		return err
+	}
+
+	resolution := template.Manifest.Resolution
+	if opts.Resolution != nil {
+		resolution = *opts.Resolution
+	}
+	_, err = p.ask("Resolution", resolution.String(), func(answer string) error {
+		resolution, err = parseResolution(answer)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	opts.Resolution = nil
+	if resolution != template.Manifest.Resolution {
+		opts.Resolution = &resolution
+	}
+
+	title := names.Title
+	if opts.Title != "" {
+		title = opts.Title
+	}
+	if title, err = p.ask("Window title", title, func(string) error { return nil }); err != nil {
+		return err
+	}
+	opts.Title = ""
+	if title != names.Title {
+		opts.Title = title
+	}
+
+	opts.Confirm = func(plan *projectPlan) (bool, error) {
+		printPlanSummary(plan)
+		fmt.Println("\nTo create the same project without the questions:")
+		fmt.Println("  " + wizardCommand(opts))
+		fmt.Println()
+		ok, err := p.confirm("Create the project?", true)
+		if err == nil && !ok {
+			fmt.Println("No project was created.")
+		}
+		return ok, err
+	}
+	return createProject(opts)
+}
+
+// askAllowHooks asks whether to run the shell commands of a template from
+// --template-dir or --template-repo, which --allow-hooks would allow
+func askAllowHooks(p *prompter, opts *projectOptions, template availableTemplate) error {
+	commands := untrustedCommands(template.Manifest, opts.Templates)
+	if len(commands) == 0 || opts.AllowHooks || opts.NoHooks {
+		return nil
+	}
+	fmt.Fprintf(p.out, "\n%s (%s) runs these shell commands in the project:\n", template.Manifest.Name, template.Source.Location())
+	for _, hook := range commands {
+		fmt.Fprintf(p.out, "  %s\n", hook)
+	}
+	allow, err := p.confirm("Run them?", false)
+	opts.AllowHooks = allow
+	return err
+}
+
+// askTemplate asks for the genre and its features, or the netcode template of the genre
+func askTemplate(p *prompter, opts *projectOptions, templates []availableTemplate, layers []*FeatureLayer) error {
+	var genres []availableTemplate
+	def := ""
+	for _, template := range templates {
+		if template.Manifest.Genre == "" && !template.Manifest.HasTag("netcode") {
+			genres = append(genres, template)
+			if template.Manifest.Name == defaultTemplate {
+				def = defaultTemplate
+			}
+		}
+	}
+	if len(genres) == 0 {
+		return errors.New("no genre templates to choose from")
+	}
+	if def == "" {
+		def = genres[0].Manifest.Name
+	}
+	genre, err := p.choose("Genre:", genres, def)
+	if err != nil {
+		return err
+	}
+
+	for _, template := range templates {
+		manifest := template.Manifest
+		if manifest.Genre != "" || !manifest.HasTag("netcode") || !manifest.HasTag(genre) {
+			continue
+		}
+		netcode, err := p.confirm("Networked multiplayer with a client and server (netcode)?", false)
+		if err != nil {
+			return err
+		}
+		if netcode {
+			fmt.Printf("  The %s template has LDtk levels and no split-screen.\n", manifest.Name)
+			opts.Template = manifest.Name
+			return nil
+		}
+		break
+	}
+
+	var features []string
+	for _, layer := range layers {
+		if _, ok := layer.Genres[genre]; !ok || layer.Default {
+			continue
+		}
+		add, err := p.confirm("Add "+layer.Description+"?", false)
+		if err != nil {
+			return err
+		}
+		if add {
+			features = append(features, layer.Name)
+		}
+	}
+	if len(features) == 0 {
+		opts.Template = genre
+		return nil
+	}
+	opts.Genre, opts.With = genre, features
+	return nil
+}
+
+// printPlanSummary prints what the wizard is about to create, `new --dry-run`
+// lists every file
+func printPlanSummary(plan *projectPlan) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  Directory\t%s\n", plan.ProjectDir)
+	fmt.Fprintf(w, "  Module\t%s\n", plan.ModulePath)
+	fmt.Fprintf(w, "  Template\t%s\n", plan.Template)
+	fmt.Fprintf(w, "  Features\t%s\n", strings.Join(plan.Features, ", "))
+	fmt.Fprintf(w, "  Resolution\t%s\n", plan.Resolution)
+	fmt.Fprintf(w, "  Window title\t%s\n", plan.Title)
+	fmt.Fprintf(w, "  Files\t%d\n", len(plan.Files))
+	for _, command := range plan.Commands {
+		fmt.Fprintf(w, "  Then runs\t%s\n", command)
+	}
+	if len(plan.Hooks) > 0 {
+		hooks := make([]string, len(plan.Hooks))
+		for i, hook := range plan.Hooks {
+			hooks[i] = hook.String()
+		}
+		fmt.Fprintf(w, "  Hooks\t%s\n", strings.Join(hooks, ", "))
+	}
+	for _, hook := range plan.BlockedHooks {
+		fmt.Fprintf(w, "  Not run\t%s\n", hook)
+	}
+	w.Flush()
+}
+
+// wizardCommand returns the `new` command line creating the project opts describe
+func wizardCommand(opts projectOptions) string {
+	args := []string{"bappacreate", "new", "--yes"}
+	flag := func(name, value string) {
+		if value != "" {
+			args = append(args, "--"+name, shellQuote(value))
+		}
+	}
+	flag("template", opts.Template)
+	flag("genre", opts.Genre)
+	flag("with", strings.Join(opts.With, ","))
+	if opts.Resolution != nil {
+		flag("resolution", opts.Resolution.String())
+	}
+	flag("title", opts.Title)
+	flag("author", opts.Author)
+	flag("template-dir", opts.Templates.Dir)
+	flag("template-repo", opts.Templates.Repo)
+	flag("bappa-version", opts.Deps.BappaVersion)
+	if opts.Deps.Offline {
+		args = append(args, "--offline")
+	}
+	if opts.Force {
+		args = append(args, "--force")
+	}
+	if opts.Merge {
+		args = append(args, "--merge")
+	}
+	if opts.NoHooks {
+		args = append(args, "--no-hooks")
+	}
+	if opts.AllowHooks {
+		args = append(args, "--allow-hooks")
+	}
+	return strings.Join(append(args, shellQuote(opts.Name)), " ")
+}
+
+// shellQuote quotes s for a POSIX shell when it needs it
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@,=+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
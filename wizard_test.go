@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct{ s, want string }{
+		{s: "johndoe/space-game", want: "johndoe/space-game"},
+		{s: "https://example.com/templates.git@v1.0", want: "https://example.com/templates.git@v1.0"},
+		{s: "split,ldtk", want: "split,ldtk"},
+		{s: "", want: "''"},
+		{s: "Space Game", want: "'Space Game'"},
+		{s: "John's Game", want: `'John'\''s Game'`},
+		{s: "'", want: `''\'''`},
+		{s: "$HOME", want: "'$HOME'"},
+		{s: "a\\b", want: `'a\b'`},
+		{s: "~/templates", want: "'~/templates'"},
+		{s: "two\nlines", want: "'two\nlines'"},
+	}
+	_, err := exec.LookPath("sh")
+	shell := err == nil
+	for _, tt := range tests {
+		got := shellQuote(tt.s)
+		if got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.s, got, tt.want)
+		}
+		if !shell {
+			continue
+		}
+		// The shell gives back the string
+		out, err := exec.Command("sh", "-c", "printf '%s|' "+got).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.s+"|" {
+			t.Errorf("sh reads shellQuote(%q) as %q", tt.s, strings.TrimSuffix(string(out), "|"))
+		}
+	}
+}
+
+func TestWizardCommand(t *testing.T) {
+	tests := []struct {
+		opts projectOptions
+		want string
+	}{
+		{
+			opts: projectOptions{Name: "johndoe/dungeon", Template: "topdown"},
+			want: "bappacreate new --yes --template topdown johndoe/dungeon",
+		},
+		{
+			opts: projectOptions{
+				Name:       "github.com/johndoe/space-fight",
+				Genre:      "platformer",
+				With:       []string{"split", "ldtk"},
+				Resolution: &Resolution{Width: 1280, Height: 720},
+				Title:      "Space Fight",
+				Author:     "John O'Doe",
+			},
+			want: `bappacreate new --yes --genre platformer --with split,ldtk --resolution 1280x720 --title 'Space Fight' --author 'John O'\''Doe' github.com/johndoe/space-fight`,
+		},
+		{
+			opts: projectOptions{
+				Name:      "me/game",
+				Template:  "arena",
+				Templates: templateOptions{Dir: "/srv/game templates"},
+				Deps:      dependencyOptions{Offline: true, BappaVersion: "v0.1.0"},
+				Force:     true,
+				NoHooks:   true,
+			},
+			want: "bappacreate new --yes --template arena --template-dir '/srv/game templates' --bappa-version v0.1.0 --offline --force --no-hooks me/game",
+		},
+		{
+			opts: projectOptions{Name: "me/game", Template: "arena", Templates: templateOptions{Repo: "git@example.com:studio/templates.git@v2"}, Merge: true, AllowHooks: true},
+			want: "bappacreate new --yes --template arena --template-repo git@example.com:studio/templates.git@v2 --merge --allow-hooks me/game",
+		},
+	}
+	for _, tt := range tests {
+		if got := wizardCommand(tt.opts); got != tt.want {
+			t.Errorf("wizardCommand =\n%s\nwant\n%s", got, tt.want)
+		}
+	}
+}
+
+func TestParseResolution(t *testing.T) {
+	for s, want := range map[string]Resolution{
+		"640x360":    {Width: 640, Height: 360},
+		" 1280X720 ": {Width: 1280, Height: 720},
+	} {
+		if got, err := parseResolution(s); err != nil || got != want {
+			t.Errorf("parseResolution(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "640", "640x", "x360", "0x360", "640x-1", "640x360x2", "wide", "640 x 360"} {
+		if _, err := parseResolution(s); err == nil || !strings.Contains(err.Error(), "expected <width>x<height> like 640x360") {
+			t.Errorf("parseResolution(%q) = %v, want it rejected", s, err)
+		}
+	}
+}
+
+// TestAskTemplate answers the wizard's template questions
+func TestAskTemplate(t *testing.T) {
+	templates := builtinTemplates(t)
+	layers, err := loadLayers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		answers  string // one per line
+		template string
+		genre    string
+		with     []string
+	}{
+		{answers: "\n\n\n", template: defaultTemplate},
+		{answers: "platformer\nn\nn\ny\n", genre: "platformer", with: []string{"split"}},
+		{answers: "1\nno\nyes\nyes\n", genre: "platformer", with: []string{"ldtk", "split"}},
+		{answers: "sandbox\n", template: "sandbox"},
+		{answers: "platformer\ny\n", template: "platformer-netcode"},
+		{answers: "9\nchess\ntopdown\nmaybe\ny\n\n", genre: "topdown", with: []string{"ldtk"}},
+	}
+	for _, tt := range tests {
+		p := &prompter{in: bufio.NewReader(strings.NewReader(tt.answers)), out: io.Discard}
+		var opts projectOptions
+		if err := askTemplate(p, &opts, templates, layers); err != nil {
+			t.Errorf("answering %q: %v", tt.answers, err)
+			continue
+		}
+		if opts.Template != tt.template || opts.Genre != tt.genre || !slices.Equal(opts.With, tt.with) {
+			t.Errorf("answering %q gives template %q, genre %q with %q, want %q, %q with %q", tt.answers, opts.Template, opts.Genre, opts.With, tt.template, tt.genre, tt.with)
+		}
+	}
+
+	p := &prompter{in: bufio.NewReader(strings.NewReader("topdown\n")), out: io.Discard}
+	if err := askTemplate(p, &projectOptions{}, templates, layers); err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("running out of answers = %v, want the wizard cancelled", err)
+	}
+}
+
+func TestAskAllowHooks(t *testing.T) {
+	manifest := &TemplateManifest{Name: "arena", Hooks: []Hook{{Step: "gofmt"}, {Run: "make assets"}}}
+	template := availableTemplate{Manifest: manifest, Source: dirTemplate{"/srv/templates/arena"}}
+	external := templateOptions{Dir: "/srv/templates"}
+	tests := []struct {
+		answers string
+		opts    projectOptions
+		allow   bool
+	}{
+		{answers: "y\n", opts: projectOptions{Templates: external}, allow: true},
+		{answers: "\n", opts: projectOptions{Templates: external}},
+		{answers: "", opts: projectOptions{Templates: external, AllowHooks: true}, allow: true},
+		{answers: "", opts: projectOptions{Templates: external, NoHooks: true}},
+		{answers: "", opts: projectOptions{}}, // installed templates aren't asked about
+	}
+	for _, tt := range tests {
+		p := &prompter{in: bufio.NewReader(strings.NewReader(tt.answers)), out: io.Discard}
+		opts := tt.opts
+		if err := askAllowHooks(p, &opts, template); err != nil {
+			t.Errorf("answering %q: %v", tt.answers, err)
+			continue
+		}
+		if opts.AllowHooks != tt.allow {
+			t.Errorf("answering %q with %+v allows hooks: %v, want %v", tt.answers, tt.opts, opts.AllowHooks, tt.allow)
+		}
+	}
+}